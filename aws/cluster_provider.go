@@ -8,27 +8,67 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/emr"
 	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+	"sort"
 	"strings"
 	"time"
 	"trino-exporter/trino"
 )
 
+const (
+	providerName = "aws"
+	tagPrefix    = "tag_"
+)
+
+// DefaultNameTemplate names the clusters after the emr cluster name
+const DefaultNameTemplate = "{{.Name}}"
+
+// NameData are the fields of the emr cluster available to the name template, eg: {{.Tags.env}}-{{.Name}}
+type NameData struct {
+	ID   string
+	Name string
+	Tags map[string]string
+}
+
+// ClusterProvider discovers the emr clusters with trino installed, the clusters are labelled with the allowed
+// tags (as tag_<key>), with the provider name and the emr cluster id when metadataLabels is set, and named
+// with the name template
 type ClusterProvider struct {
-	emrClient *emr.EMR
-	ec2Client *ec2.EC2
-	cache     *cache.Cache
+	emrClient      *emr.EMR
+	ec2Client      *ec2.EC2
+	cache          *cache.Cache
+	tags           []string
+	metadataLabels bool
+	nameTemplate   trino.NameTemplate
 }
 
-func NewClusterProvider() *ClusterProvider {
+// NewClusterProvider returns a provider naming the clusters with nameTemplate, DefaultNameTemplate when empty
+func NewClusterProvider(tags []string, metadataLabels bool, nameTemplate string) (*ClusterProvider, error) {
+	if nameTemplate == "" {
+		nameTemplate = DefaultNameTemplate
+	}
+
+	template, err := trino.NewNameTemplate(nameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, keys := range trino.SanitizedLabelCollisions(tagPrefix, tags) {
+		logrus.Warnf("emr cluster tags %s are all exported as %s, only one of them is kept", strings.Join(keys, ", "), name)
+	}
+
 	sess := session.Must(session.NewSessionWithOptions(session.Options{
 		SharedConfigState: session.SharedConfigEnable,
 	}))
 
 	return &ClusterProvider{
-		emrClient: emr.New(sess),
-		ec2Client: ec2.New(sess),
-		cache:     cache.New(60*time.Minute, 24*time.Hour),
-	}
+		emrClient:      emr.New(sess),
+		ec2Client:      ec2.New(sess),
+		cache:          cache.New(60*time.Minute, 24*time.Hour),
+		tags:           tags,
+		metadataLabels: metadataLabels,
+		nameTemplate:   template,
+	}, nil
 }
 
 const cacheKey = "master"
@@ -59,20 +99,51 @@ func (c *ClusterProvider) listTargetMasters(ctx context.Context) (map[string]tri
 		return nil, err
 	}
 
-	for _, cluster := range clusters {
+	for name, cluster := range c.nameClusters(clusters) {
 		master, err := c.getClusterMasterInstance(cluster)
 		if err != nil {
 			return nil, err
 		}
 
-		clusterWithMaster[*cluster.Cluster.Name] = trino.ClusterInfo{
-			Host: fmt.Sprintf("http://%s:8889", master),
+		clusterWithMaster[name] = trino.ClusterInfo{
+			Host:   fmt.Sprintf("http://%s:8889", master),
+			Labels: clusterLabels(cluster.Cluster, c.tags, c.metadataLabels),
 		}
 	}
 
 	return clusterWithMaster, nil
 }
 
+// nameClusters names the emr clusters with the name template, the clusters are named in order of id so that
+// the emr cluster keeping a duplicated name is always the same one. Clusters without a name are skipped
+func (c *ClusterProvider) nameClusters(clusters []*emr.DescribeClusterOutput) map[string]*emr.DescribeClusterOutput {
+	sorted := make([]*emr.DescribeClusterOutput, len(clusters))
+	copy(sorted, clusters)
+	sort.Slice(sorted, func(i, j int) bool {
+		return aws.StringValue(sorted[i].Cluster.Id) < aws.StringValue(sorted[j].Cluster.Id)
+	})
+
+	named := make(map[string]*emr.DescribeClusterOutput, len(sorted))
+	for _, cluster := range sorted {
+		id := aws.StringValue(cluster.Cluster.Id)
+
+		name, err := c.nameTemplate.Name(nameData(cluster.Cluster))
+		if err != nil {
+			logrus.Warnf("emr cluster %s skipped: %s", id, err)
+			continue
+		}
+
+		if _, present := named[name]; present {
+			logrus.Warnf("emr cluster %s skipped, cluster name %s already used by another emr cluster", id, name)
+			continue
+		}
+
+		named[name] = cluster
+	}
+
+	return named
+}
+
 func (c *ClusterProvider) listTargetClusters(ctx context.Context) ([]*emr.DescribeClusterOutput, error) {
 	req := &emr.ListClustersInput{
 		ClusterStates: aws.StringSlice([]string{"WAITING"}),
@@ -171,3 +242,39 @@ func isTrinoInstalled(descr *emr.DescribeClusterOutput) bool {
 	}
 	return false
 }
+
+func nameData(cluster *emr.Cluster) NameData {
+	tags := make(map[string]string, len(cluster.Tags))
+	for _, tag := range cluster.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return NameData{
+		ID:   aws.StringValue(cluster.Id),
+		Name: aws.StringValue(cluster.Name),
+		Tags: tags,
+	}
+}
+
+// clusterLabels returns the allowed tags of the emr cluster, with the provider name and the cluster id when
+// metadata is set
+func clusterLabels(cluster *emr.Cluster, tags []string, metadata bool) map[string]string {
+	labels := make(map[string]string, len(tags))
+	if metadata {
+		labels["provider"] = providerName
+		labels["cluster_id"] = aws.StringValue(cluster.Id)
+	}
+
+	allowed := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		allowed[tag] = true
+	}
+
+	for _, tag := range cluster.Tags {
+		if key := aws.StringValue(tag.Key); allowed[key] {
+			labels[trino.SanitizeLabelName(tagPrefix, key)] = aws.StringValue(tag.Value)
+		}
+	}
+
+	return labels
+}
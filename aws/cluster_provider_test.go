@@ -0,0 +1,84 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/emr"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"trino-exporter/trino"
+)
+
+func TestClusterLabels(t *testing.T) {
+	cluster := &emr.Cluster{
+		Id:   aws.String("j-2AXXXXXXGAPLF"),
+		Name: aws.String("analytics"),
+		Tags: []*emr.Tag{
+			{Key: aws.String("team"), Value: aws.String("data")},
+			{Key: aws.String("cost-center"), Value: aws.String("cc-42")},
+			{Key: aws.String("owner"), Value: aws.String("alice")},
+		},
+	}
+
+	require.Equal(t, map[string]string{
+		"tag_team":        "data",
+		"tag_cost_center": "cc-42",
+	}, clusterLabels(cluster, []string{"team", "cost-center", "env"}, false))
+
+	require.Equal(t, map[string]string{
+		"provider":   "aws",
+		"cluster_id": "j-2AXXXXXXGAPLF",
+		"tag_team":   "data",
+	}, clusterLabels(cluster, []string{"team"}, true))
+
+	require.Empty(t, clusterLabels(cluster, nil, false))
+}
+
+func TestNameTemplate(t *testing.T) {
+	cluster := &emr.Cluster{
+		Id:   aws.String("j-2AXXXXXXGAPLF"),
+		Name: aws.String("analytics"),
+		Tags: []*emr.Tag{{Key: aws.String("env"), Value: aws.String("prod")}},
+	}
+
+	tests := map[string]string{
+		DefaultNameTemplate:          "analytics",
+		"{{.Tags.env}}-{{.Name}}":    "prod-analytics",
+		"{{.Name}}-{{.ID}}":          "analytics-j-2AXXXXXXGAPLF",
+		"{{.Name}}{{.Tags.missing}}": "analytics",
+	}
+
+	for text, expected := range tests {
+		template, err := trino.NewNameTemplate(text)
+		require.NoError(t, err)
+
+		name, err := template.Name(nameData(cluster))
+		require.NoError(t, err)
+		require.Equal(t, expected, name)
+	}
+}
+
+func TestNameClusters(t *testing.T) {
+	template, err := trino.NewNameTemplate("{{.Tags.env}}")
+	require.NoError(t, err)
+
+	provider := &ClusterProvider{nameTemplate: template}
+
+	cluster := func(id string, env string) *emr.DescribeClusterOutput {
+		tags := make([]*emr.Tag, 0)
+		if env != "" {
+			tags = append(tags, &emr.Tag{Key: aws.String("env"), Value: aws.String(env)})
+		}
+		return &emr.DescribeClusterOutput{Cluster: &emr.Cluster{Id: aws.String(id), Tags: tags}}
+	}
+
+	named := provider.nameClusters([]*emr.DescribeClusterOutput{
+		cluster("j-3", "prod"),
+		cluster("j-2", ""),
+		cluster("j-1", "prod"),
+		cluster("j-4", "dev"),
+	})
+
+	require.Len(t, named, 2)
+	require.Equal(t, "j-1", aws.StringValue(named["prod"].Cluster.Id))
+	require.Equal(t, "j-4", aws.StringValue(named["dev"].Cluster.Id))
+}
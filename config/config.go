@@ -0,0 +1,409 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/url"
+	"time"
+	"trino-exporter/trino"
+)
+
+const (
+	DefaultClusterDomain       = "cluster.local"
+	DefaultQueryMaxLabelValues = 20
+	DefaultQueryMaxRunning     = 10
+	DefaultWorkerParallelism   = 4
+)
+
+// DefaultResourceGroups are the root resource groups monitored when none is configured
+var DefaultResourceGroups = []string{"global"}
+
+// Config declares the clusters to monitor, the discovery providers and the enabled collectors
+type Config struct {
+	Clusters   []Cluster  `yaml:"clusters"`
+	Discovery  Discovery  `yaml:"discovery"`
+	Collectors Collectors `yaml:"collectors"`
+}
+
+// Cluster is a statically configured coordinator running the flavor distribution (trino, prestosql, prestodb),
+// the auth settings (username, password_file, password_env, token_file, oauth2, tls) are inlined. Labels are attached to every metric of the cluster,
+// timeout bounds the duration of its collection and poll_interval overrides the --poll-interval of the cluster
+type Cluster struct {
+	Name               string            `yaml:"name"`
+	Url                string            `yaml:"url"`
+	Labels             map[string]string `yaml:"labels,omitempty"`
+	Timeout            time.Duration     `yaml:"timeout,omitempty"`
+	PollInterval       time.Duration     `yaml:"poll_interval,omitempty"`
+	Flavor             string            `yaml:"flavor,omitempty"`
+	trino.AuthSettings `yaml:",inline"`
+}
+
+// Discovery configures the discovery providers, nil providers are disabled
+type Discovery struct {
+	AWS        *AWSDiscovery        `yaml:"aws,omitempty"`
+	Kubernetes *KubernetesDiscovery `yaml:"kubernetes,omitempty"`
+}
+
+// AWSDiscovery discovers the emr clusters with trino installed, the flavor and the auth settings apply to all of them.
+// The emr cluster tags listed in tags are attached to the metrics as tag_<key>, metadata_labels attaches also
+// provider and cluster_id, name_template renders the cluster names from the ID, the Name and the Tags of the emr clusters
+type AWSDiscovery struct {
+	Tags               []string `yaml:"tags,omitempty"`
+	MetadataLabels     bool     `yaml:"metadata_labels,omitempty"`
+	NameTemplate       string   `yaml:"name_template,omitempty"`
+	Flavor             string   `yaml:"flavor,omitempty"`
+	trino.AuthSettings `yaml:",inline"`
+}
+
+// KubernetesDiscovery discovers the services matching label_selector with an http port, the flavor and the
+// auth settings apply to all of them. The service labels listed in service_labels are attached to the
+// metrics as label_<key>, metadata_labels attaches also provider, namespace and service, name_template
+// renders the cluster names from the Namespace, the Service, the Labels and the Annotations of the services
+type KubernetesDiscovery struct {
+	ClusterDomain      string   `yaml:"cluster_domain,omitempty"`
+	LabelSelector      string   `yaml:"label_selector,omitempty"`
+	ServiceLabels      []string `yaml:"service_labels,omitempty"`
+	MetadataLabels     bool     `yaml:"metadata_labels,omitempty"`
+	NameTemplate       string   `yaml:"name_template,omitempty"`
+	Flavor             string   `yaml:"flavor,omitempty"`
+	trino.AuthSettings `yaml:",inline"`
+}
+
+// Collectors enables the optional collectors, the statistics collector is always enabled
+type Collectors struct {
+	Info           Toggle         `yaml:"info"`
+	Jmx            JmxCollector   `yaml:"jmx"`
+	Worker         Workers        `yaml:"worker"`
+	Memory         Toggle         `yaml:"memory"`
+	Node           Toggle         `yaml:"node"`
+	ResourceGroups ResourceGroups `yaml:"resource_groups"`
+	Queries        Queries        `yaml:"queries"`
+	Federate       Toggle         `yaml:"federate"`
+}
+
+type Toggle struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// JmxCollector exports the mbean attributes of metrics_file, the curated default list when empty
+type JmxCollector struct {
+	Enabled     bool   `yaml:"enabled"`
+	MetricsFile string `yaml:"metrics_file,omitempty"`
+}
+
+// Workers scrapes up to parallelism workers of a cluster concurrently, the worker mbean attributes are the ones of
+// metrics_file. The credentials of the coordinator are sent to the workers only when send_credentials is set
+type Workers struct {
+	Enabled         bool   `yaml:"enabled"`
+	MetricsFile     string `yaml:"metrics_file,omitempty"`
+	Parallelism     *int   `yaml:"parallelism,omitempty"`
+	SendCredentials bool   `yaml:"send_credentials,omitempty"`
+}
+
+type ResourceGroups struct {
+	Enabled bool     `yaml:"enabled"`
+	Roots   []string `yaml:"roots,omitempty"`
+}
+
+type Queries struct {
+	Enabled        bool `yaml:"enabled"`
+	MaxLabelValues *int `yaml:"max_label_values,omitempty"`
+	MaxRunning     *int `yaml:"max_running,omitempty"`
+}
+
+// Load reads and validates the config file, unknown fields are rejected
+func Load(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return Config{}, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	config.warnInsecure(path)
+	config.warnLabelCollisions(path)
+
+	return config, nil
+}
+
+// Validate checks the whole config without reading the secrets, the error names the invalid entry
+func (c Config) Validate() error {
+	names := make(map[string]bool, len(c.Clusters))
+	for i, cluster := range c.Clusters {
+		if cluster.Name == "" {
+			return fmt.Errorf("clusters[%d]: name is required", i)
+		}
+
+		if names[cluster.Name] {
+			return fmt.Errorf("clusters[%d]: duplicated cluster name %s", i, cluster.Name)
+		}
+		names[cluster.Name] = true
+
+		if err := cluster.validate(); err != nil {
+			return fmt.Errorf("cluster %s: %w", cluster.Name, err)
+		}
+	}
+
+	if c.Discovery.AWS != nil {
+		if err := c.Discovery.AWS.validate(); err != nil {
+			return fmt.Errorf("aws discovery: %w", err)
+		}
+	}
+
+	if c.Discovery.Kubernetes != nil {
+		if err := c.Discovery.Kubernetes.validate(); err != nil {
+			return fmt.Errorf("kubernetes discovery: %w", err)
+		}
+	}
+
+	if err := c.Collectors.validate(); err != nil {
+		return fmt.Errorf("collectors: %w", err)
+	}
+
+	return nil
+}
+
+func (c Cluster) validate() error {
+	if err := ValidateUrl(c.Url); err != nil {
+		return err
+	}
+
+	if err := trino.ValidateLabels(c.Labels); err != nil {
+		return err
+	}
+
+	if c.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+
+	if c.PollInterval < 0 {
+		return errors.New("poll_interval must not be negative")
+	}
+
+	if _, err := trino.ParseFlavor(c.Flavor); err != nil {
+		return err
+	}
+
+	return c.AuthSettings.Validate()
+}
+
+func (a AWSDiscovery) validate() error {
+	if err := validateKeys("tags", a.Tags); err != nil {
+		return err
+	}
+	if err := validateNameTemplate(a.NameTemplate); err != nil {
+		return err
+	}
+	if _, err := trino.ParseFlavor(a.Flavor); err != nil {
+		return err
+	}
+	return a.AuthSettings.Validate()
+}
+
+func (k KubernetesDiscovery) validate() error {
+	if err := validateKeys("service_labels", k.ServiceLabels); err != nil {
+		return err
+	}
+	if err := validateNameTemplate(k.NameTemplate); err != nil {
+		return err
+	}
+	if _, err := trino.ParseFlavor(k.Flavor); err != nil {
+		return err
+	}
+	return k.AuthSettings.Validate()
+}
+
+// validateNameTemplate checks the name template parses, empty templates select the provider default
+func validateNameTemplate(text string) error {
+	if text == "" {
+		return nil
+	}
+
+	_, err := trino.NewNameTemplate(text)
+	return err
+}
+
+func validateKeys(field string, keys []string) error {
+	for _, key := range keys {
+		if key == "" {
+			return fmt.Errorf("%s must not be empty", field)
+		}
+	}
+	return nil
+}
+
+// ValidateUrl checks the url of a coordinator is an absolute http(s) url
+func ValidateUrl(raw string) error {
+	if raw == "" {
+		return errors.New("url is required")
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %w", raw, err)
+	}
+
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid url %s: expected http(s)://host:port", raw)
+	}
+
+	return nil
+}
+
+func (c Collectors) validate() error {
+	if c.Queries.MaxLabelValues != nil && *c.Queries.MaxLabelValues < 0 {
+		return errors.New("queries max_label_values must not be negative")
+	}
+
+	if c.Queries.MaxRunning != nil && *c.Queries.MaxRunning < 0 {
+		return errors.New("queries max_running must not be negative")
+	}
+
+	if c.Worker.Parallelism != nil && *c.Worker.Parallelism < 1 {
+		return errors.New("worker parallelism must be positive")
+	}
+
+	for _, root := range c.ResourceGroups.Roots {
+		if root == "" {
+			return errors.New("resource_groups roots must not be empty")
+		}
+	}
+
+	return nil
+}
+
+// MaxLabelValuesOrDefault returns the configured max label values of the query collector or its default
+func (q Queries) MaxLabelValuesOrDefault() int {
+	if q.MaxLabelValues == nil {
+		return DefaultQueryMaxLabelValues
+	}
+	return *q.MaxLabelValues
+}
+
+// MaxRunningOrDefault returns the configured max running queries of the query collector or its default
+func (q Queries) MaxRunningOrDefault() int {
+	if q.MaxRunning == nil {
+		return DefaultQueryMaxRunning
+	}
+	return *q.MaxRunning
+}
+
+// ParallelismOrDefault returns the configured parallelism of the worker collector or its default
+func (w Workers) ParallelismOrDefault() int {
+	if w.Parallelism == nil {
+		return DefaultWorkerParallelism
+	}
+	return *w.Parallelism
+}
+
+// RootsOrDefault returns the configured root resource groups or the default ones
+func (r ResourceGroups) RootsOrDefault() []string {
+	if len(r.Roots) == 0 {
+		return DefaultResourceGroups
+	}
+	return r.Roots
+}
+
+// ClusterDomainOrDefault returns the configured cluster domain or cluster.local
+func (k KubernetesDiscovery) ClusterDomainOrDefault() string {
+	if k.ClusterDomain == "" {
+		return DefaultClusterDomain
+	}
+	return k.ClusterDomain
+}
+
+func (c Config) warnInsecure(path string) {
+	for _, cluster := range c.Clusters {
+		if cluster.TLS.InsecureSkipVerify {
+			logrus.Warnf("config file %s, cluster %s: tls certificate verification disabled", path, cluster.Name)
+		}
+	}
+
+	if c.Discovery.AWS != nil && c.Discovery.AWS.TLS.InsecureSkipVerify {
+		logrus.Warnf("config file %s, aws discovery: tls certificate verification disabled", path)
+	}
+
+	if c.Discovery.Kubernetes != nil && c.Discovery.Kubernetes.TLS.InsecureSkipVerify {
+		logrus.Warnf("config file %s, kubernetes discovery: tls certificate verification disabled", path)
+	}
+}
+
+// warnLabelCollisions warns about the cluster labels with the name of a label of the collector metrics, the
+// metrics having that label keep their own value
+func (c Config) warnLabelCollisions(path string) {
+	for _, cluster := range c.Clusters {
+		for _, name := range trino.LabelCollisions(cluster.Labels) {
+			logrus.Warnf("config file %s, cluster %s: label %s collides with a label of the collector metrics, those metrics keep their own value", path, cluster.Name, name)
+		}
+	}
+}
+
+// ClusterProvider provides the clusters of the config file with their auth settings
+func (c Config) ClusterProvider() trino.ClusterProvider {
+	file := make(trino.AuthFile, len(c.Clusters))
+	for _, cluster := range c.Clusters {
+		file[cluster.Name] = cluster.AuthSettings
+	}
+
+	return trino.NewAuthClusterProvider(staticClusterProvider(c.Clusters), file)
+}
+
+type staticClusterProvider []Cluster
+
+func (p staticClusterProvider) Provide() (map[string]trino.ClusterInfo, error) {
+	clusters := make(map[string]trino.ClusterInfo, len(p))
+	for _, cluster := range p {
+		flavor, _ := trino.ParseFlavor(cluster.Flavor)
+		clusters[cluster.Name] = trino.ClusterInfo{
+			Host:         cluster.Url,
+			Labels:       cluster.Labels,
+			Timeout:      cluster.Timeout,
+			PollInterval: cluster.PollInterval,
+			Flavor:       flavor,
+		}
+	}
+	return clusters, nil
+}
+
+// WithAuth applies the auth settings to all the clusters of a discovery provider
+func WithAuth(provider trino.ClusterProvider, settings trino.AuthSettings) trino.ClusterProvider {
+	return trino.NewAuthClusterProvider(provider, trino.AuthFile{trino.DefaultAuthEntry: settings})
+}
+
+// WithFlavor applies the flavor to all the clusters of a discovery provider
+func WithFlavor(provider trino.ClusterProvider, flavor string) trino.ClusterProvider {
+	parsed, _ := trino.ParseFlavor(flavor)
+	return flavorClusterProvider{provider: provider, flavor: parsed}
+}
+
+type flavorClusterProvider struct {
+	provider trino.ClusterProvider
+	flavor   trino.Flavor
+}
+
+// Provide returns a copy of the clusters with the flavor, the discovery providers return their cached map
+// shared by the concurrent scrapes
+func (p flavorClusterProvider) Provide() (map[string]trino.ClusterInfo, error) {
+	clusters, err := p.provider.Provide()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]trino.ClusterInfo, len(clusters))
+	for name, cluster := range clusters {
+		cluster.Flavor = p.flavor
+		result[name] = cluster
+	}
+
+	return result, nil
+}
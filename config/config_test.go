@@ -0,0 +1,171 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"trino-exporter/trino"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+clusters:
+  - name: analytics
+    url: https://analytics.example.com:8443
+    username: monitoring
+    password_env: TRINO_PASSWORD
+    timeout: 5s
+    poll_interval: 1m
+    labels:
+      team: data
+    tls:
+      server_name: trino.example.com
+  - name: adhoc
+    url: http://10.0.0.1:8080
+    flavor: prestosql
+discovery:
+  kubernetes:
+    label_selector: app=trino
+    service_labels: [app.kubernetes.io/team]
+    name_template: '{{.Namespace}}-{{.Service}}'
+    token_file: /var/run/secrets/trino/token
+collectors:
+  info:
+    enabled: true
+  queries:
+    enabled: true
+    max_label_values: 0
+  worker:
+    enabled: true
+    send_credentials: true
+`)
+
+	config, err := Load(path)
+	require.NoError(t, err)
+
+	require.Len(t, config.Clusters, 2)
+	require.Equal(t, "monitoring", config.Clusters[0].Username)
+	require.Equal(t, 5*time.Second, config.Clusters[0].Timeout)
+	require.Equal(t, time.Minute, config.Clusters[0].PollInterval)
+	require.Equal(t, "trino.example.com", config.Clusters[0].TLS.ServerName)
+	require.Equal(t, "prestosql", config.Clusters[1].Flavor)
+
+	require.Nil(t, config.Discovery.AWS)
+	require.Equal(t, "app=trino", config.Discovery.Kubernetes.LabelSelector)
+	require.Equal(t, []string{"app.kubernetes.io/team"}, config.Discovery.Kubernetes.ServiceLabels)
+	require.Equal(t, "{{.Namespace}}-{{.Service}}", config.Discovery.Kubernetes.NameTemplate)
+	require.Equal(t, DefaultClusterDomain, config.Discovery.Kubernetes.ClusterDomainOrDefault())
+
+	require.True(t, config.Collectors.Info.Enabled)
+	require.False(t, config.Collectors.Jmx.Enabled)
+	require.Equal(t, 0, config.Collectors.Queries.MaxLabelValuesOrDefault())
+	require.Equal(t, DefaultQueryMaxRunning, config.Collectors.Queries.MaxRunningOrDefault())
+	require.Equal(t, DefaultResourceGroups, config.Collectors.ResourceGroups.RootsOrDefault())
+	require.True(t, config.Collectors.Worker.SendCredentials)
+	require.Equal(t, DefaultWorkerParallelism, config.Collectors.Worker.ParallelismOrDefault())
+}
+
+func TestLoadRejectsInvalidConfigs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{"unknown field", "clusters:\n  - name: a\n    url: http://a:8080\n    pasword_file: /secret\n", "field pasword_file not found"},
+		{"missing name", "clusters:\n  - url: http://a:8080\n", "clusters[0]: name is required"},
+		{"duplicated name", "clusters:\n  - {name: a, url: 'http://a:8080'}\n  - {name: a, url: 'http://b:8080'}\n", "duplicated cluster name a"},
+		{"invalid url", "clusters:\n  - {name: a, url: 'a:8080'}\n", "cluster a: invalid url a:8080"},
+		{"reserved label", "clusters:\n  - {name: a, url: 'http://a:8080', labels: {cluster_name: b}}\n", "label cluster_name is reserved"},
+		{"invalid label", "clusters:\n  - {name: a, url: 'http://a:8080', labels: {cost-center: b}}\n", "invalid label name \"cost-center\""},
+		{"invalid timeout", "clusters:\n  - {name: a, url: 'http://a:8080', timeout: 5x}\n", "cannot unmarshal"},
+		{"poll interval", "clusters:\n  - {name: a, url: 'http://a:8080', poll_interval: -1s}\n", "cluster a: poll_interval must not be negative"},
+		{"auth", "clusters:\n  - {name: a, url: 'http://a:8080', password_env: P, token_file: /t}\n", "cluster a: password, token_file and oauth2 are mutually exclusive"},
+		{"flavor", "clusters:\n  - {name: a, url: 'http://a:8080', flavor: mysql}\n", "cluster a: unknown flavor mysql"},
+		{"discovery flavor", "discovery:\n  aws:\n    flavor: mysql\n", "aws discovery: unknown flavor mysql"},
+		{"tags", "discovery:\n  aws:\n    tags: ['']\n", "aws discovery: tags must not be empty"},
+		{"name template", "discovery:\n  kubernetes:\n    name_template: '{{.Service'\n", "kubernetes discovery: invalid name template"},
+		{"collectors", "collectors:\n  queries:\n    max_running: -1\n", "collectors: queries max_running must not be negative"},
+		{"worker parallelism", "collectors:\n  worker:\n    parallelism: 0\n", "collectors: worker parallelism must be positive"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, test.content))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.message)
+		})
+	}
+}
+
+func TestClusterProvider(t *testing.T) {
+	require.NoError(t, os.Setenv("TRINO_EXPORTER_TEST_CONFIG_PASSWORD", "secret"))
+	t.Cleanup(func() { _ = os.Unsetenv("TRINO_EXPORTER_TEST_CONFIG_PASSWORD") })
+
+	config := Config{Clusters: []Cluster{{
+		Name:         "analytics",
+		Url:          "http://analytics:8080",
+		Labels:       map[string]string{"team": "data"},
+		Timeout:      time.Second,
+		PollInterval: time.Minute,
+		Flavor:       "prestodb",
+		AuthSettings: trino.AuthSettings{Username: "monitoring", PasswordEnv: "TRINO_EXPORTER_TEST_CONFIG_PASSWORD"},
+	}}}
+
+	clusters, err := config.ClusterProvider().Provide()
+	require.NoError(t, err)
+
+	cluster := clusters["analytics"]
+	require.Equal(t, "http://analytics:8080", cluster.Host)
+	require.Equal(t, map[string]string{"team": "data"}, cluster.Labels)
+	require.Equal(t, time.Second, cluster.Timeout)
+	require.Equal(t, time.Minute, cluster.PollInterval)
+	require.Equal(t, trino.Credentials{Username: "monitoring", Password: "secret"}, cluster.Credentials)
+	require.Equal(t, trino.FlavorPrestoDB, cluster.Flavor)
+}
+
+func TestWithFlavor(t *testing.T) {
+	provider := staticClusterProvider{{Name: "discovered", Url: "http://discovered:8080"}}
+
+	clusters, err := WithFlavor(provider, "prestosql").Provide()
+	require.NoError(t, err)
+	require.Equal(t, trino.FlavorPrestoSQL, clusters["discovered"].Flavor)
+}
+
+// sharedClusterProvider returns always the same map, as the discovery providers returning their cache
+type sharedClusterProvider map[string]trino.ClusterInfo
+
+func (p sharedClusterProvider) Provide() (map[string]trino.ClusterInfo, error) {
+	return p, nil
+}
+
+func TestWithFlavorConcurrentProvide(t *testing.T) {
+	shared := sharedClusterProvider{
+		"first":  {Host: "http://first:8080"},
+		"second": {Host: "http://second:8080"},
+	}
+	provider := WithFlavor(shared, "prestodb")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clusters, err := provider.Provide()
+			require.NoError(t, err)
+			require.Equal(t, trino.FlavorPrestoDB, clusters["first"].Flavor)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, trino.Flavor(""), shared["first"].Flavor)
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const exporterNamespace = "trino_exporter"
+
+// Reloader runs the reload of the configuration on SIGHUP and on POST requests, reloads are serialized and
+// a failed reload keeps the previous configuration. The configuration loaded on startup counts as the first
+// successful reload
+type Reloader struct {
+	reload func() error
+
+	mutex         sync.Mutex
+	lastSuccess   prometheus.Gauge
+	lastTimestamp prometheus.Gauge
+}
+
+func NewReloader(reload func() error) *Reloader {
+	reloader := &Reloader{
+		reload: reload,
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: exporterNamespace,
+			Name:      "config_last_reload_successful",
+			Help:      "Whether the last configuration reload succeeded.",
+		}),
+		lastTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: exporterNamespace,
+			Name:      "config_last_reload_success_timestamp_seconds",
+			Help:      "Timestamp of the last successful configuration reload.",
+		}),
+	}
+
+	reloader.lastSuccess.Set(1)
+	reloader.lastTimestamp.SetToCurrentTime()
+
+	return reloader
+}
+
+// Reload reloads the configuration recording the result
+func (r *Reloader) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	start := time.Now()
+	if err := r.reload(); err != nil {
+		logrus.Errorf("configuration reload failed, keeping the previous configuration: %s", err)
+		r.lastSuccess.Set(0)
+		return err
+	}
+
+	logrus.Infof("configuration reloaded in %s", time.Since(start))
+	r.lastSuccess.Set(1)
+	r.lastTimestamp.SetToCurrentTime()
+
+	return nil
+}
+
+// WatchSignals reloads the configuration on every SIGHUP until ctx is done
+func (r *Reloader) WatchSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			_ = r.Reload()
+		}
+	}
+}
+
+// ServeHTTP reloads the configuration on POST, failed reloads are answered with the error
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST requests reload the configuration", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.Reload(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *Reloader) Describe(ch chan<- *prometheus.Desc) {
+	r.lastSuccess.Describe(ch)
+	r.lastTimestamp.Describe(ch)
+}
+
+func (r *Reloader) Collect(out chan<- prometheus.Metric) {
+	r.lastSuccess.Collect(out)
+	r.lastTimestamp.Collect(out)
+}
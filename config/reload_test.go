@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReloader(t *testing.T) {
+	var err error
+	reloads := 0
+	reloader := NewReloader(func() error {
+		reloads++
+		return err
+	})
+
+	require.Equal(t, 1.0, testutil.ToFloat64(reloader.lastSuccess))
+	startup := testutil.ToFloat64(reloader.lastTimestamp)
+	require.Greater(t, startup, 0.0)
+
+	recorder := httptest.NewRecorder()
+	reloader.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/-/reload", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	require.Equal(t, 0, reloads)
+
+	err = errors.New("invalid config file")
+	recorder = httptest.NewRecorder()
+	reloader.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "invalid config file")
+	require.Equal(t, 0.0, testutil.ToFloat64(reloader.lastSuccess))
+	require.Equal(t, startup, testutil.ToFloat64(reloader.lastTimestamp))
+
+	err = nil
+	recorder = httptest.NewRecorder()
+	reloader.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, 1.0, testutil.ToFloat64(reloader.lastSuccess))
+	require.Equal(t, 2, reloads)
+}
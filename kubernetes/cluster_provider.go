@@ -10,22 +10,43 @@ import (
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"net/url"
+	"sort"
+	"strings"
 	"time"
 	"trino-exporter/trino"
 )
 
 const (
-	svcPortName = "http"
+	svcPortName  = "http"
+	providerName = "kubernetes"
+	labelPrefix  = "label_"
 )
 
+// DefaultNameTemplate names the clusters after the namespace and the name of the service
+const DefaultNameTemplate = "{{.Namespace}}/{{.Service}}"
+
+// NameData are the fields of the service available to the name template, eg: {{index .Labels "app.kubernetes.io/instance"}}
+type NameData struct {
+	Namespace   string
+	Service     string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// ClusterProvider discovers the services matching the label selector, the clusters are labelled with the
+// allowed service labels (as label_<key>), with the provider name, the namespace and the service name when
+// metadataLabels is set, and named with the name template
 type ClusterProvider struct {
 	k8sClient        k8s.Interface
 	cache            *cache.Cache
 	clusterDomain    string
 	svcLabelSelector string
+	svcLabels        []string
+	metadataLabels   bool
+	nameTemplate     trino.NameTemplate
 }
 
-func NewInClusterProvider(clusterDomain string, svcLabelSelector string) (*ClusterProvider, error) {
+func NewInClusterProvider(clusterDomain string, svcLabelSelector string, svcLabels []string, metadataLabels bool, nameTemplate string) (*ClusterProvider, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, err
@@ -36,16 +57,33 @@ func NewInClusterProvider(clusterDomain string, svcLabelSelector string) (*Clust
 		return nil, err
 	}
 
-	return NewClusterProvider(k8sClient, clusterDomain, svcLabelSelector), nil
+	return NewClusterProvider(k8sClient, clusterDomain, svcLabelSelector, svcLabels, metadataLabels, nameTemplate)
 }
 
-func NewClusterProvider(k8sClient k8s.Interface, clusterDomain string, svcLabelSelector string) *ClusterProvider {
+// NewClusterProvider returns a provider naming the clusters with nameTemplate, DefaultNameTemplate when empty
+func NewClusterProvider(k8sClient k8s.Interface, clusterDomain string, svcLabelSelector string, svcLabels []string, metadataLabels bool, nameTemplate string) (*ClusterProvider, error) {
+	if nameTemplate == "" {
+		nameTemplate = DefaultNameTemplate
+	}
+
+	template, err := trino.NewNameTemplate(nameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, keys := range trino.SanitizedLabelCollisions(labelPrefix, svcLabels) {
+		logrus.Warnf("service labels %s are all exported as %s, only one of them is kept", strings.Join(keys, ", "), name)
+	}
+
 	return &ClusterProvider{
 		k8sClient:        k8sClient,
 		clusterDomain:    clusterDomain,
 		svcLabelSelector: svcLabelSelector,
+		svcLabels:        svcLabels,
+		metadataLabels:   metadataLabels,
+		nameTemplate:     template,
 		cache:            cache.New(10*time.Minute, 24*time.Hour),
-	}
+	}, nil
 }
 
 const cacheKey = "k8s-cluster-provider"
@@ -66,6 +104,7 @@ func (k *ClusterProvider) Provide() (map[string]trino.ClusterInfo, error) {
 		return nil, err
 	}
 
+	candidates := make([]v12.Service, 0)
 	for _, ns := range namespaces.Items {
 		services, err := k.k8sClient.CoreV1().Services(ns.Name).List(ctx, v1.ListOptions{
 			LabelSelector: k.svcLabelSelector,
@@ -75,24 +114,49 @@ func (k *ClusterProvider) Provide() (map[string]trino.ClusterInfo, error) {
 			return nil, err
 		}
 
-		for _, svc := range services.Items {
-			servicePort, err := portByName(svc.Spec.Ports, svcPortName)
-			if err != nil {
-				logrus.Debug(err)
-				continue
-			}
+		candidates = append(candidates, services.Items...)
+	}
 
-			svcUrl, err := url.Parse(fmt.Sprintf("http://%s.%s.svc.%s:%d", svc.Name, svc.Namespace, k.clusterDomain, servicePort.Port))
-			if err != nil {
-				return nil, err
-			}
+	// services are named in order of namespace and name so that the service keeping a duplicated name is always the same one
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Namespace != candidates[j].Namespace {
+			return candidates[i].Namespace < candidates[j].Namespace
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
 
-			name := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
+	for _, svc := range candidates {
+		servicePort, err := portByName(svc.Spec.Ports, svcPortName)
+		if err != nil {
+			logrus.Debug(err)
+			continue
+		}
 
-			logrus.Infof("discovered service %s", svc.Name)
-			coordinators[name] = trino.ClusterInfo{
-				Host: svcUrl.String(),
-			}
+		svcUrl, err := url.Parse(fmt.Sprintf("http://%s.%s.svc.%s:%d", svc.Name, svc.Namespace, k.clusterDomain, servicePort.Port))
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := k.nameTemplate.Name(NameData{
+			Namespace:   svc.Namespace,
+			Service:     svc.Name,
+			Labels:      svc.Labels,
+			Annotations: svc.Annotations,
+		})
+		if err != nil {
+			logrus.Warnf("service %s/%s skipped: %s", svc.Namespace, svc.Name, err)
+			continue
+		}
+
+		if _, present := coordinators[name]; present {
+			logrus.Warnf("service %s/%s skipped, cluster name %s already used by another service", svc.Namespace, svc.Name, name)
+			continue
+		}
+
+		logrus.Infof("discovered service %s", svc.Name)
+		coordinators[name] = trino.ClusterInfo{
+			Host:   svcUrl.String(),
+			Labels: k.serviceLabels(svc),
 		}
 	}
 
@@ -100,6 +164,25 @@ func (k *ClusterProvider) Provide() (map[string]trino.ClusterInfo, error) {
 	return coordinators, nil
 }
 
+// serviceLabels returns the allowed labels of the service, with the provider name, the namespace and the
+// service name when the metadata labels are enabled
+func (k *ClusterProvider) serviceLabels(svc v12.Service) map[string]string {
+	labels := make(map[string]string, len(k.svcLabels))
+	if k.metadataLabels {
+		labels["provider"] = providerName
+		labels["namespace"] = svc.Namespace
+		labels["service"] = svc.Name
+	}
+
+	for _, key := range k.svcLabels {
+		if value, present := svc.Labels[key]; present {
+			labels[trino.SanitizeLabelName(labelPrefix, key)] = value
+		}
+	}
+
+	return labels
+}
+
 func portByName(ports []v12.ServicePort, name string) (v12.ServicePort, error) {
 	for _, port := range ports {
 		if port.Name == name {
@@ -180,7 +180,8 @@ func TestClusterProviderKubernetes(t *testing.T) {
 
 	client := k8sClient{clientset}
 
-	provider := NewClusterProvider(client, "cluster.local", "")
+	provider, err := NewClusterProvider(client, "cluster.local", "", nil, false, "")
+	require.NoError(t, err)
 
 	clusters, err := provider.Provide()
 	require.NoError(t, err)
@@ -195,7 +196,8 @@ func TestClusterProviderKubernetesWithSvcLabelSelector(t *testing.T) {
 
 	client := k8sClient{clientset}
 
-	provider := NewClusterProvider(client, "cluster.local", "trino.distribution=trino-exportersql")
+	provider, err := NewClusterProvider(client, "cluster.local", "trino.distribution=trino-exportersql", nil, false, "")
+	require.NoError(t, err)
 
 	clusters, err := provider.Provide()
 	require.NoError(t, err)
@@ -203,3 +205,108 @@ func TestClusterProviderKubernetesWithSvcLabelSelector(t *testing.T) {
 	require.Len(t, clusters, 3)
 
 }
+
+func TestClusterProviderKubernetesLabels(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "analytics"}},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "trino",
+				Namespace: "analytics",
+				Labels:    map[string]string{"app.kubernetes.io/team": "data", "tier": "gold"},
+			},
+			Spec: v1.ServiceSpec{Ports: []v1.ServicePort{{Name: svcPortName, Port: 8080}}},
+		},
+	)
+
+	provider, err := NewClusterProvider(clientset, "cluster.local", "", []string{"app.kubernetes.io/team", "cost-center"}, false, "")
+	require.NoError(t, err)
+
+	clusters, err := provider.Provide()
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"label_app_kubernetes_io_team": "data",
+	}, clusters["analytics/trino"].Labels)
+
+	provider, err = NewClusterProvider(clientset, "cluster.local", "", []string{"app.kubernetes.io/team"}, true, "")
+	require.NoError(t, err)
+
+	clusters, err = provider.Provide()
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"provider":                     "kubernetes",
+		"namespace":                    "analytics",
+		"service":                      "trino",
+		"label_app_kubernetes_io_team": "data",
+	}, clusters["analytics/trino"].Labels)
+}
+
+func TestClusterProviderKubernetesNameTemplate(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "analytics"}},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "trino",
+				Namespace:   "analytics",
+				Labels:      map[string]string{"app.kubernetes.io/instance": "adhoc"},
+				Annotations: map[string]string{"example.com/env": "prod"},
+			},
+			Spec: v1.ServiceSpec{Ports: []v1.ServicePort{{Name: svcPortName, Port: 8080}}},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "trino-canary",
+				Namespace: "analytics",
+				Labels:    map[string]string{"app.kubernetes.io/instance": "adhoc"},
+			},
+			Spec: v1.ServiceSpec{Ports: []v1.ServicePort{{Name: svcPortName, Port: 8080}}},
+		},
+	)
+
+	provider, err := NewClusterProvider(clientset, "cluster.local", "", nil, false,
+		`{{index .Labels "app.kubernetes.io/instance"}}{{with index .Annotations "example.com/env"}}-{{.}}{{end}}`)
+	require.NoError(t, err)
+
+	clusters, err := provider.Provide()
+	require.NoError(t, err)
+
+	require.Len(t, clusters, 2)
+	require.Equal(t, "http://trino.analytics.svc.cluster.local:8080", clusters["adhoc-prod"].Host)
+	require.Equal(t, "http://trino-canary.analytics.svc.cluster.local:8080", clusters["adhoc"].Host)
+
+	_, err = NewClusterProvider(clientset, "cluster.local", "", nil, false, "{{.Service")
+	require.Error(t, err)
+}
+
+func TestClusterProviderKubernetesNameCollisions(t *testing.T) {
+	service := func(namespace string, name string, instance string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/instance": instance},
+			},
+			Spec: v1.ServiceSpec{Ports: []v1.ServicePort{{Name: svcPortName, Port: 8080}}},
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "analytics"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "adhoc"}},
+		service("analytics", "trino", "shared"),
+		service("adhoc", "trino-b", "shared"),
+		service("adhoc", "trino-a", "shared"),
+		service("adhoc", "trino-unnamed", ""),
+	)
+
+	provider, err := NewClusterProvider(clientset, "cluster.local", "", nil, false, `{{index .Labels "app.kubernetes.io/instance"}}`)
+	require.NoError(t, err)
+
+	clusters, err := provider.Provide()
+	require.NoError(t, err)
+
+	require.Len(t, clusters, 1)
+	require.Equal(t, "http://trino-a.adhoc.svc.cluster.local:8080", clusters["shared"].Host)
+}
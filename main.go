@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	"io/ioutil"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync"
+	"time"
 	"trino-exporter/aws"
+	"trino-exporter/config"
 	k8s "trino-exporter/kubernetes"
 	"trino-exporter/trino"
 )
@@ -21,37 +27,237 @@ func main() {
 	k8sAutoDiscovery := flag.Bool("k8s-autodiscovery", false, "autodiscover cluster in k8s (may require permissions)")
 
 	k8sDiscoveryLabelSelector := flag.String("k8s-svc-label-selector", "", "k8s service label selector")
-	clustersRaw := flag.String("cluster", "", "clusters to monitor separated by ',' eg: http://127.0.0.1:8889,http://127.0.0.1:8888")
+	k8sDiscoveryLabels := flag.String("k8s-svc-labels", "", "k8s service labels attached to the metrics of the discovered clusters as label_<key>, separated by ','")
+	k8sNameTemplate := flag.String("k8s-name-template", k8s.DefaultNameTemplate, "go template of the cluster_name of the discovered services, with the fields Namespace, Service, Labels and Annotations")
+	awsNameTemplate := flag.String("aws-name-template", aws.DefaultNameTemplate, "go template of the cluster_name of the discovered emr clusters, with the fields ID, Name and Tags")
+	awsDiscoveryTags := flag.String("aws-tags", "", "emr cluster tags attached to the metrics of the discovered clusters as tag_<key>, separated by ','")
+	awsMetadataLabels := flag.Bool("aws-metadata-labels", false, "attach also the provider and cluster_id labels to the metrics of the discovered clusters")
+	k8sMetadataLabels := flag.Bool("k8s-metadata-labels", false, "attach also the provider, namespace and service labels to the metrics of the discovered clusters")
+	clustersRaw := flag.String("cluster", "", "trino clusters to monitor separated by ',' as name=url pairs or urls named after the url eg: adhoc=http://127.0.0.1:8889,http://127.0.0.1:8888 (presto clusters need a flavor, declare them in the config file)")
+	targetsFile := flag.String("cluster-targets-file", "", "file with a trino cluster per line as name=url pair or url, read again on every collection")
+	configFile := flag.String("config-file", "", "yaml file declaring the clusters, the discovery providers and the collectors, the flags explicitly set override it")
+	authFile := flag.String("auth-file", "", "yaml file with the credentials of the clusters by cluster name, the 'default' entry applies to the clusters not listed, it does not set the flavor (not allowed with config-file)")
+	parallelism := flag.Int("parallelism", 10, "max clusters collected concurrently")
+	retries := flag.Int("retries", 2, "retries of the requests failed with transient errors (connection errors, 502, 503, 504)")
+	retryBackoff := flag.Duration("retry-backoff", 100*time.Millisecond, "backoff before the first retry, doubled for each following retry and jittered")
+	breakerFailures := flag.Int("circuit-breaker-failures", 5, "consecutive failed collections opening the circuit breaker of a cluster (0 = disabled)")
+	breakerOpenDuration := flag.Duration("circuit-breaker-open-duration", time.Minute, "time a cluster is not collected once its circuit breaker opens")
+	breakerMaxOpenDuration := flag.Duration("circuit-breaker-max-open-duration", 10*time.Minute, "max time a cluster is not collected, the open time doubles after every failed probe")
+	scrapeTimeoutOffset := flag.Duration("scrape-timeout-offset", 500*time.Millisecond, "offset subtracted from the prometheus scrape timeout to stop the coordinator requests in time")
+	pollInterval := flag.Duration("poll-interval", 0, "collect the clusters in background every interval serving the last snapshot on scrape (0 = collect on scrape)")
+	pollStaleAfter := flag.Duration("poll-stale-after", 0, "age after which a cluster snapshot is reported as stale (default 3 * the poll interval of the cluster)")
+	enableLifecycle := flag.Bool("web-enable-lifecycle", false, "reload the configuration also on POST /-/reload, SIGHUP always reloads it")
+
+	prestoNamespace := flag.Bool("presto-namespace", false, "export every trino_cluster metric also as presto_cluster metric, for dashboards built on presto")
+	federate := flag.Bool("federate", false, "re-expose the native coordinator /metrics endpoint adding the cluster_name label")
+	infoMetrics := flag.Bool("info-metrics", false, "export version, environment and uptime from the coordinator /v1/info endpoint")
+	jmxMetrics := flag.Bool("jmx-metrics", false, "export coordinator mbean attributes from the /v1/jmx/mbean endpoint")
+	jmxMetricsFile := flag.String("jmx-metrics-file", "", "json file with the mbean attributes to export, defaults to a curated list of query manager, memory, spill and jvm attributes")
+	workerMetrics := flag.Bool("worker-metrics", false, "scrape directly the workers reported by the coordinators for status and jmx metrics")
+	workerJmxMetricsFile := flag.String("worker-jmx-metrics-file", "", "json file with the worker mbean attributes to export, defaults to task executor and jvm gc attributes")
+	workerParallelism := flag.Int("worker-parallelism", config.DefaultWorkerParallelism, "max workers of a cluster scraped concurrently")
+	workerCredentials := flag.Bool("worker-send-credentials", false, "send the credentials of the coordinator (password or token) also to its workers")
+	memoryMetrics := flag.Bool("memory-metrics", false, "export memory pool metrics from the coordinator /v1/cluster/memory endpoint")
+	nodeMetrics := flag.Bool("node-metrics", false, "export per node metrics from the coordinator /v1/node endpoints")
+	resourceGroupMetrics := flag.Bool("resource-group-metrics", false, "export per resource group metrics from the coordinator /v1/resourceGroupState endpoint")
+	resourceGroupsRaw := flag.String("resource-groups", strings.Join(config.DefaultResourceGroups, ","), "root resource groups to monitor separated by ',', the root groups of the running queries are discovered automatically")
+	queryMetrics := flag.Bool("query-metrics", false, "export per query metrics from the coordinator /v1/query endpoint")
+	queryMaxLabelValues := flag.Int("query-max-label-values", config.DefaultQueryMaxLabelValues, "max users, sources and resource groups exported per cluster, the others are aggregated as 'other' (0 = unlimited)")
+	queryMaxRunning := flag.Int("query-max-running", config.DefaultQueryMaxRunning, "max running queries exported individually per cluster")
 
 	flag.Parse()
 
 	log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
 
+	// the credentials of the config file would be silently replaced by the default entry of the auth file
+	if *configFile != "" && *authFile != "" {
+		log.Fatal("--auth-file and --config-file are mutually exclusive, declare the credentials in the config file")
+	}
+
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	// without config file every flag applies, with a config file only the flags explicitly set override it
+	override := func(name string) bool {
+		return *configFile == "" || explicit[name]
+	}
+
+	// loadConfig reads the config file applying the flag overrides, on startup and on every reload
+	loadConfig := func() (config.Config, error) {
+		cfg := config.Config{}
+		if *configFile != "" {
+			var err error
+			if cfg, err = config.Load(*configFile); err != nil {
+				return config.Config{}, err
+			}
+		}
+
+		if override("aws-autodiscovery") {
+			if !*awsAutoDiscovery {
+				cfg.Discovery.AWS = nil
+			} else if cfg.Discovery.AWS == nil {
+				cfg.Discovery.AWS = &config.AWSDiscovery{}
+			}
+		}
+
+		if override("k8s-autodiscovery") {
+			if !*k8sAutoDiscovery {
+				cfg.Discovery.Kubernetes = nil
+			} else if cfg.Discovery.Kubernetes == nil {
+				cfg.Discovery.Kubernetes = &config.KubernetesDiscovery{}
+			}
+		}
+
+		if override("k8s-svc-label-selector") && cfg.Discovery.Kubernetes != nil {
+			cfg.Discovery.Kubernetes.LabelSelector = *k8sDiscoveryLabelSelector
+		}
+
+		if override("k8s-svc-labels") && cfg.Discovery.Kubernetes != nil {
+			cfg.Discovery.Kubernetes.ServiceLabels = splitNonEmpty(*k8sDiscoveryLabels)
+		}
+
+		if override("k8s-metadata-labels") && cfg.Discovery.Kubernetes != nil {
+			cfg.Discovery.Kubernetes.MetadataLabels = *k8sMetadataLabels
+		}
+
+		if override("k8s-name-template") && cfg.Discovery.Kubernetes != nil {
+			cfg.Discovery.Kubernetes.NameTemplate = *k8sNameTemplate
+		}
+
+		if override("aws-tags") && cfg.Discovery.AWS != nil {
+			cfg.Discovery.AWS.Tags = splitNonEmpty(*awsDiscoveryTags)
+		}
+
+		if override("aws-metadata-labels") && cfg.Discovery.AWS != nil {
+			cfg.Discovery.AWS.MetadataLabels = *awsMetadataLabels
+		}
+
+		if override("aws-name-template") && cfg.Discovery.AWS != nil {
+			cfg.Discovery.AWS.NameTemplate = *awsNameTemplate
+		}
+
+		collectorsConfig := &cfg.Collectors
+		if override("info-metrics") {
+			collectorsConfig.Info.Enabled = *infoMetrics
+		}
+		if override("jmx-metrics") {
+			collectorsConfig.Jmx.Enabled = *jmxMetrics
+		}
+		if override("jmx-metrics-file") {
+			collectorsConfig.Jmx.MetricsFile = *jmxMetricsFile
+		}
+		if override("worker-metrics") {
+			collectorsConfig.Worker.Enabled = *workerMetrics
+		}
+		if override("worker-jmx-metrics-file") {
+			collectorsConfig.Worker.MetricsFile = *workerJmxMetricsFile
+		}
+		if override("worker-parallelism") {
+			collectorsConfig.Worker.Parallelism = workerParallelism
+		}
+		if override("worker-send-credentials") {
+			collectorsConfig.Worker.SendCredentials = *workerCredentials
+		}
+		if override("memory-metrics") {
+			collectorsConfig.Memory.Enabled = *memoryMetrics
+		}
+		if override("node-metrics") {
+			collectorsConfig.Node.Enabled = *nodeMetrics
+		}
+		if override("resource-group-metrics") {
+			collectorsConfig.ResourceGroups.Enabled = *resourceGroupMetrics
+		}
+		if override("resource-groups") {
+			collectorsConfig.ResourceGroups.Roots = splitNonEmpty(*resourceGroupsRaw)
+		}
+		if override("query-metrics") {
+			collectorsConfig.Queries.Enabled = *queryMetrics
+		}
+		if override("query-max-label-values") {
+			collectorsConfig.Queries.MaxLabelValues = queryMaxLabelValues
+		}
+		if override("query-max-running") {
+			collectorsConfig.Queries.MaxRunning = queryMaxRunning
+		}
+		if override("federate") {
+			collectorsConfig.Federate.Enabled = *federate
+		}
+
+		// the flag overrides are validated along with the config file they are applied to
+		if err := cfg.Validate(); err != nil {
+			return config.Config{}, fmt.Errorf("invalid configuration: %w", err)
+		}
+
+		return cfg, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	registry := prometheus.NewRegistry()
 
-	var clusterProvider = trino.NewMultiClusterProvider()
+	client := trino.NewClient(trino.WithRetries(*retries, *retryBackoff))
+	builder := newExporterBuilder(client, &FlagClusterProvider{flag: *clustersRaw, targetsFile: *targetsFile}, *authFile)
+
+	provider, collectors, err := builder.build(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	clusterProvider.Add(FlagClusterProvider{flag: *clustersRaw})
+	exporter := trino.NewExporter(provider, *parallelism, collectors...)
 
-	if *awsAutoDiscovery {
-		log.Info("enabled aws discovery")
-		clusterProvider.Add(aws.NewClusterProvider())
+	if *breakerFailures > 0 {
+		exporter = exporter.WithCircuitBreaker(*breakerFailures, *breakerOpenDuration, *breakerMaxOpenDuration)
 	}
 
-	if *k8sAutoDiscovery {
-		log.Info("enabled k8s in cluster discovery")
+	if *prestoNamespace {
+		log.Info("enabled presto namespace metrics")
+		exporter = exporter.WithPrestoNamespace()
+	}
 
-		provider, err := k8s.NewInClusterProvider("cluster.local", *k8sDiscoveryLabelSelector)
+	if err := trino.RegisterExporterMetrics(registry); err != nil {
+		log.Fatal(err)
+	}
+
+	reloader := config.NewReloader(func() error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		provider, collectors, err := builder.build(cfg)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		clusterProvider.Add(provider)
+		exporter.Reload(provider, collectors...)
+
+		log.Infof("the flags %s are not reloaded, their changes require a restart", strings.Join(restartFlags, ", "))
+		return nil
+	})
+
+	registry.MustRegister(reloader)
+	go reloader.WatchSignals(context.Background())
+
+	if *pollInterval > 0 {
+		log.Infof("enabled background polling every %s", *pollInterval)
+
+		poller := trino.NewPoller(exporter, *pollInterval, *pollStaleAfter)
+		go poller.Run(context.Background())
+
+		registry.MustRegister(poller)
+		http.Handle(*metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	} else {
+		http.Handle(*metricsPath, trino.NewScrapeHandler(registry, exporter, *scrapeTimeoutOffset, promhttp.HandlerOpts{}))
 	}
 
-	registry.MustRegister(trino.NewCollector(clusterProvider))
+	if *enableLifecycle {
+		log.Info("enabled configuration reload on POST /-/reload")
+		http.Handle("/-/reload", reloader)
+	}
 
-	http.Handle(*metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 	http.Handle("/healthz", http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		if _, err := writer.Write([]byte("OK")); err != nil {
 			log.Error(err)
@@ -67,20 +273,408 @@ func main() {
 	}
 }
 
+// restartFlags are the flags applied once on startup, the reloads keep their startup values
+var restartFlags = []string{
+	"--parallelism", "--retries", "--retry-backoff", "--circuit-breaker-failures", "--circuit-breaker-open-duration",
+	"--circuit-breaker-max-open-duration", "--presto-namespace", "--poll-interval", "--poll-stale-after", "--scrape-timeout-offset",
+}
+
+// FlagClusterProvider provides the clusters of the cluster flag and of the targets file, the targets are
+// name=url pairs or plain urls named after the url. The targets file has a target per line, empty lines
+// and lines starting with # are ignored, and is read again every time the clusters are provided: when
+// it can not be read or is invalid the last valid targets are provided
 type FlagClusterProvider struct {
-	flag string
+	flag        string
+	targetsFile string
+
+	mutex   sync.Mutex
+	targets map[string]trino.ClusterInfo
+}
+
+// Validate checks the cluster flag and the targets file, on startup and on every reload
+func (f *FlagClusterProvider) Validate() error {
+	clusters, err := f.flagTargets()
+	if err != nil {
+		return err
+	}
+
+	if f.targetsFile == "" {
+		return nil
+	}
+
+	targets, err := f.readTargets(clusters)
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.targets = targets
+
+	return nil
+}
+
+func (f *FlagClusterProvider) Provide() (map[string]trino.ClusterInfo, error) {
+	clusters, err := f.flagTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.targetsFile == "" {
+		return clusters, nil
+	}
+
+	targets, err := f.readTargets(clusters)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err != nil {
+		if f.targets == nil {
+			return nil, err
+		}
+
+		log.Warnf("%s, keeping the last valid targets", err)
+		return f.targets, nil
+	}
+
+	f.targets = targets
+
+	return targets, nil
+}
+
+func (f *FlagClusterProvider) flagTargets() (map[string]trino.ClusterInfo, error) {
+	clusters := make(map[string]trino.ClusterInfo)
+	if err := addTargets(clusters, splitNonEmpty(f.flag)); err != nil {
+		return nil, fmt.Errorf("invalid cluster flag: %w", err)
+	}
+	return clusters, nil
+}
+
+// readTargets returns the clusters of the flag followed by the targets of the file
+func (f *FlagClusterProvider) readTargets(flagClusters map[string]trino.ClusterInfo) (map[string]trino.ClusterInfo, error) {
+	data, err := ioutil.ReadFile(f.targetsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			targets = append(targets, line)
+		}
+	}
+
+	clusters := make(map[string]trino.ClusterInfo, len(flagClusters)+len(targets))
+	for name, cluster := range flagClusters {
+		clusters[name] = cluster
+	}
+
+	if err := addTargets(clusters, targets); err != nil {
+		return nil, fmt.Errorf("invalid targets file %s: %w", f.targetsFile, err)
+	}
+
+	return clusters, nil
+}
+
+func addTargets(clusters map[string]trino.ClusterInfo, targets []string) error {
+	for _, target := range targets {
+		name, host := parseTarget(target)
+		if name == "" || host == "" {
+			return fmt.Errorf("invalid target %s, expected name=url or url", target)
+		}
+
+		if err := config.ValidateUrl(host); err != nil {
+			return fmt.Errorf("target %s: %w", name, err)
+		}
+
+		if _, present := clusters[name]; present {
+			return fmt.Errorf("duplicated cluster name %s", name)
+		}
+
+		clusters[name] = trino.ClusterInfo{
+			Host: host,
+		}
+	}
+	return nil
+}
+
+// parseTarget splits a name=url target, plain urls (eg: http://host:8080/?a=b) are named after the url
+func parseTarget(target string) (string, string) {
+	separator := strings.Index(target, "=")
+	if separator < 0 || strings.Contains(target[:separator], "/") {
+		return target, target
+	}
+	return strings.TrimSpace(target[:separator]), strings.TrimSpace(target[separator+1:])
+}
+
+// exporterBuilder builds the cluster provider and the collectors of a config, the discovery providers and the jmx
+// collectors with unchanged settings and the stateful collectors are reused across the reloads so that their caches
+// and their descs survive
+type exporterBuilder struct {
+	client   *trino.Client
+	clusters *FlagClusterProvider
+	authFile string
+
+	discoveries map[string]discovery
+	jmx         map[string]jmxCollector
+	collector   trino.Collector
+	info        *trino.InfoCollector
+}
+
+// discovery is a discovery provider created with settings
+type discovery struct {
+	settings interface{}
+	provider trino.ClusterProvider
 }
 
-func (f FlagClusterProvider) Provide() (map[string]trino.ClusterInfo, error) {
-	cnt := len(f.flag)
-	clustersToMonitor := make(map[string]trino.ClusterInfo, cnt)
-	if cnt != 0 {
-		clusters := strings.Split(f.flag, ",")
-		for _, c := range clusters {
-			clustersToMonitor[c] = trino.ClusterInfo{
-				Host: c,
+// jmxCollector is a collector of mbean attributes created with settings, its descs are registered for good as
+// presto twins so that it is created again only when its settings change
+type jmxCollector struct {
+	settings  interface{}
+	collector trino.ClusterCollector
+}
+
+// workerSettings are the settings the worker collector is created with
+type workerSettings struct {
+	metrics         []trino.JmxMetric
+	parallelism     int
+	sendCredentials bool
+}
+
+func newExporterBuilder(client *trino.Client, clusters *FlagClusterProvider, authFile string) *exporterBuilder {
+	return &exporterBuilder{
+		client:      client,
+		clusters:    clusters,
+		authFile:    authFile,
+		discoveries: make(map[string]discovery),
+		jmx:         make(map[string]jmxCollector),
+		collector:   trino.NewCollector(client),
+		info:        trino.NewInfoCollector(client),
+	}
+}
+
+func (b *exporterBuilder) build(cfg config.Config) (trino.ClusterProvider, []trino.ClusterCollector, error) {
+	if err := b.clusters.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := b.checkClusterNames(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	discoveries := make(map[string]discovery)
+
+	provider, err := b.clusterProvider(cfg, discoveries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jmx := make(map[string]jmxCollector)
+
+	collectors, err := b.clusterCollectors(cfg.Collectors, jmx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b.discoveries = discoveries
+	b.jmx = jmx
+
+	return provider, collectors, nil
+}
+
+// checkClusterNames rejects the clusters of the config file named as a cluster of the cluster flag or of the
+// targets file, the provider would otherwise fail every collection
+func (b *exporterBuilder) checkClusterNames(cfg config.Config) error {
+	clusters, err := b.clusters.Provide()
+	if err != nil {
+		return err
+	}
+
+	for _, cluster := range cfg.Clusters {
+		if _, present := clusters[cluster.Name]; present {
+			return fmt.Errorf("cluster %s declared both in the config file and in the cluster flag or targets file", cluster.Name)
+		}
+	}
+
+	return nil
+}
+
+// discovery returns the discovery provider of the previous build when its settings did not change
+func (b *exporterBuilder) discovery(discoveries map[string]discovery, name string, settings interface{}, create func() (trino.ClusterProvider, error)) (trino.ClusterProvider, error) {
+	if previous, present := b.discoveries[name]; present && reflect.DeepEqual(previous.settings, settings) {
+		discoveries[name] = previous
+		return previous.provider, nil
+	}
+
+	log.Infof("enabled %s discovery", name)
+
+	provider, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	discoveries[name] = discovery{settings: settings, provider: provider}
+
+	return provider, nil
+}
+
+// jmxCollector returns the jmx collector of the previous build when its settings did not change
+func (b *exporterBuilder) jmxCollector(jmx map[string]jmxCollector, name string, settings interface{}, create func() (trino.ClusterCollector, error)) (trino.ClusterCollector, error) {
+	if previous, present := b.jmx[name]; present && reflect.DeepEqual(previous.settings, settings) {
+		jmx[name] = previous
+		return previous.collector, nil
+	}
+
+	collector, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	jmx[name] = jmxCollector{settings: settings, collector: collector}
+
+	return collector, nil
+}
+
+// clusterProvider returns the provider of the clusters of the config file, of the cluster flag and of the
+// enabled discovery providers, the auth file overrides their credentials
+func (b *exporterBuilder) clusterProvider(cfg config.Config, discoveries map[string]discovery) (trino.ClusterProvider, error) {
+	clusterProvider := trino.NewMultiClusterProvider()
+
+	clusterProvider.Add(cfg.ClusterProvider())
+	clusterProvider.Add(b.clusters)
+
+	if cfg.Discovery.AWS != nil {
+		settings := *cfg.Discovery.AWS
+		settings.AuthSettings = trino.AuthSettings{}
+		settings.Flavor = ""
+
+		provider, err := b.discovery(discoveries, "aws", settings, func() (trino.ClusterProvider, error) {
+			return aws.NewClusterProvider(settings.Tags, settings.MetadataLabels, settings.NameTemplate)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		clusterProvider.Add(config.WithAuth(config.WithFlavor(provider, cfg.Discovery.AWS.Flavor), cfg.Discovery.AWS.AuthSettings))
+	}
+
+	if cfg.Discovery.Kubernetes != nil {
+		settings := *cfg.Discovery.Kubernetes
+		settings.AuthSettings = trino.AuthSettings{}
+		settings.Flavor = ""
+
+		provider, err := b.discovery(discoveries, "k8s", settings, func() (trino.ClusterProvider, error) {
+			return k8s.NewInClusterProvider(settings.ClusterDomainOrDefault(), settings.LabelSelector, settings.ServiceLabels, settings.MetadataLabels, settings.NameTemplate)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		clusterProvider.Add(config.WithAuth(config.WithFlavor(provider, cfg.Discovery.Kubernetes.Flavor), cfg.Discovery.Kubernetes.AuthSettings))
+	}
+
+	if b.authFile == "" {
+		return clusterProvider, nil
+	}
+
+	file, err := trino.LoadAuthFile(b.authFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return trino.NewAuthClusterProvider(clusterProvider, file), nil
+}
+
+// clusterCollectors returns the statistics collector followed by the enabled collectors
+func (b *exporterBuilder) clusterCollectors(cfg config.Collectors, jmx map[string]jmxCollector) ([]trino.ClusterCollector, error) {
+	client := b.client
+	collectors := []trino.ClusterCollector{b.collector}
+
+	if cfg.Info.Enabled {
+		log.Info("enabled info metrics")
+		collectors = append(collectors, b.info)
+	}
+
+	if cfg.Jmx.Enabled {
+		log.Info("enabled jmx metrics")
+
+		metrics := trino.DefaultJmxMetrics
+		if cfg.Jmx.MetricsFile != "" {
+			var err error
+			if metrics, err = trino.LoadJmxMetrics(cfg.Jmx.MetricsFile); err != nil {
+				return nil, err
+			}
+		}
+
+		collector, err := b.jmxCollector(jmx, "jmx", metrics, func() (trino.ClusterCollector, error) {
+			return trino.NewJmxCollector(client, metrics)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		collectors = append(collectors, collector)
+	}
+
+	if cfg.Worker.Enabled {
+		log.Info("enabled worker metrics")
+
+		metrics := trino.DefaultWorkerJmxMetrics
+		if cfg.Worker.MetricsFile != "" {
+			var err error
+			if metrics, err = trino.LoadJmxMetrics(cfg.Worker.MetricsFile); err != nil {
+				return nil, err
 			}
 		}
+
+		settings := workerSettings{metrics: metrics, parallelism: cfg.Worker.ParallelismOrDefault(), sendCredentials: cfg.Worker.SendCredentials}
+
+		collector, err := b.jmxCollector(jmx, "worker", settings, func() (trino.ClusterCollector, error) {
+			return trino.NewWorkerCollector(client, settings.metrics, settings.parallelism, settings.sendCredentials)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		collectors = append(collectors, collector)
+	}
+
+	if cfg.Memory.Enabled {
+		log.Info("enabled memory metrics")
+		collectors = append(collectors, trino.NewMemoryCollector(client))
+	}
+
+	if cfg.Node.Enabled {
+		log.Info("enabled node metrics")
+		collectors = append(collectors, trino.NewNodeCollector(client))
+	}
+
+	if cfg.ResourceGroups.Enabled {
+		log.Info("enabled resource group metrics")
+		collectors = append(collectors, trino.NewResourceGroupCollector(client, cfg.ResourceGroups.RootsOrDefault()))
+	}
+
+	if cfg.Queries.Enabled {
+		log.Info("enabled query metrics")
+		collectors = append(collectors, trino.NewQueryCollector(client, cfg.Queries.MaxLabelValuesOrDefault(), cfg.Queries.MaxRunningOrDefault()))
+	}
+
+	if cfg.Federate.Enabled {
+		log.Info("enabled native metrics federation")
+		collectors = append(collectors, trino.NewFederationCollector(client))
+	}
+
+	return collectors, nil
+}
+
+func splitNonEmpty(raw string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
 	}
-	return clustersToMonitor, nil
+	return values
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"trino-exporter/config"
+	"trino-exporter/trino"
+)
+
+func TestFlagClusterProvider(t *testing.T) {
+	targetsFile := filepath.Join(t.TempDir(), "targets")
+	require.NoError(t, ioutil.WriteFile(targetsFile, []byte(`
+# batch clusters
+batch = https://batch.example.com:8443
+
+http://10.2.3.5:8889/?source=exporter
+`), 0600))
+
+	clusters, err := (&FlagClusterProvider{
+		flag:        "adhoc=http://10.2.3.4:8889, http://127.0.0.1:8888",
+		targetsFile: targetsFile,
+	}).Provide()
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]trino.ClusterInfo{
+		"adhoc":                                 {Host: "http://10.2.3.4:8889"},
+		"http://127.0.0.1:8888":                 {Host: "http://127.0.0.1:8888"},
+		"batch":                                 {Host: "https://batch.example.com:8443"},
+		"http://10.2.3.5:8889/?source=exporter": {Host: "http://10.2.3.5:8889/?source=exporter"},
+	}, clusters)
+}
+
+func TestFlagClusterProviderInvalidTargets(t *testing.T) {
+	_, err := (&FlagClusterProvider{flag: "adhoc=http://10.2.3.4:8889,adhoc=http://10.2.3.5:8889"}).Provide()
+	require.EqualError(t, err, "invalid cluster flag: duplicated cluster name adhoc")
+
+	_, err = (&FlagClusterProvider{flag: "adhoc="}).Provide()
+	require.EqualError(t, err, "invalid cluster flag: invalid target adhoc=, expected name=url or url")
+
+	err = (&FlagClusterProvider{flag: "adhoc=trino.example.com:8889"}).Validate()
+	require.EqualError(t, err, "invalid cluster flag: target adhoc: invalid url trino.example.com:8889: expected http(s)://host:port")
+
+	err = (&FlagClusterProvider{targetsFile: filepath.Join(t.TempDir(), "missing")}).Validate()
+	require.Error(t, err)
+}
+
+func TestFlagClusterProviderKeepsLastValidTargets(t *testing.T) {
+	targetsFile := filepath.Join(t.TempDir(), "targets")
+	require.NoError(t, ioutil.WriteFile(targetsFile, []byte("batch=http://10.2.3.5:8889\n"), 0600))
+
+	provider := &FlagClusterProvider{flag: "adhoc=http://10.2.3.4:8889", targetsFile: targetsFile}
+	require.NoError(t, provider.Validate())
+
+	expected := map[string]trino.ClusterInfo{
+		"adhoc": {Host: "http://10.2.3.4:8889"},
+		"batch": {Host: "http://10.2.3.5:8889"},
+	}
+
+	require.NoError(t, ioutil.WriteFile(targetsFile, []byte("batch=10.2.3.5:8889\n"), 0600))
+	require.Error(t, provider.Validate())
+
+	clusters, err := provider.Provide()
+	require.NoError(t, err)
+	require.Equal(t, expected, clusters)
+
+	require.NoError(t, os.Remove(targetsFile))
+
+	clusters, err = provider.Provide()
+	require.NoError(t, err)
+	require.Equal(t, expected, clusters)
+
+	require.NoError(t, ioutil.WriteFile(targetsFile, []byte("etl=http://10.2.3.6:8889\n"), 0600))
+
+	clusters, err = provider.Provide()
+	require.NoError(t, err)
+	require.Equal(t, map[string]trino.ClusterInfo{
+		"adhoc": {Host: "http://10.2.3.4:8889"},
+		"etl":   {Host: "http://10.2.3.6:8889"},
+	}, clusters)
+}
+
+func TestFlagClustersAreTrinoClusters(t *testing.T) {
+	authFile := filepath.Join(t.TempDir(), "auth.yaml")
+	require.NoError(t, ioutil.WriteFile(authFile, []byte("adhoc:\n  username: monitoring\n"), 0600))
+
+	builder := newExporterBuilder(trino.NewClient(), &FlagClusterProvider{flag: "adhoc=http://10.2.3.4:8889"}, authFile)
+
+	provider, _, err := builder.build(config.Config{})
+	require.NoError(t, err)
+
+	clusters, err := provider.Provide()
+	require.NoError(t, err)
+	require.Equal(t, trino.Flavor(""), clusters["adhoc"].Flavor)
+
+	require.NoError(t, ioutil.WriteFile(authFile, []byte("adhoc:\n  username: monitoring\n  flavor: prestodb\n"), 0600))
+
+	_, _, err = builder.build(config.Config{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "field flavor not found")
+}
+
+func TestExporterBuilderReusesJmxCollectors(t *testing.T) {
+	builder := newExporterBuilder(trino.NewClient(), &FlagClusterProvider{flag: "adhoc=http://10.2.3.4:8889"}, "")
+
+	cfg := config.Config{}
+	cfg.Collectors.Jmx.Enabled = true
+	cfg.Collectors.Worker.Enabled = true
+
+	_, first, err := builder.build(cfg)
+	require.NoError(t, err)
+
+	_, second, err := builder.build(cfg)
+	require.NoError(t, err)
+	require.Equal(t, describe(first), describe(second))
+
+	parallelism := 2
+	cfg.Collectors.Worker.Parallelism = &parallelism
+
+	_, third, err := builder.build(cfg)
+	require.NoError(t, err)
+	require.NotEqual(t, describe(second), describe(third))
+}
+
+func TestExporterBuilderRejectsDuplicatedClusterNames(t *testing.T) {
+	builder := newExporterBuilder(trino.NewClient(), &FlagClusterProvider{flag: "adhoc=http://10.2.3.4:8889"}, "")
+
+	cfg := config.Config{Clusters: []config.Cluster{{Name: "batch", Url: "http://10.2.3.5:8889"}}}
+
+	_, _, err := builder.build(cfg)
+	require.NoError(t, err)
+
+	cfg.Clusters = append(cfg.Clusters, config.Cluster{Name: "adhoc", Url: "http://10.2.3.6:8889"})
+
+	_, _, err = builder.build(cfg)
+	require.EqualError(t, err, "cluster adhoc declared both in the config file and in the cluster flag or targets file")
+}
+
+// describe returns the descs of the collectors, the descs of a collector created again are new pointers
+func describe(collectors []trino.ClusterCollector) map[*prometheus.Desc]bool {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		for _, collector := range collectors {
+			collector.Describe(ch)
+		}
+		close(ch)
+	}()
+
+	descs := make(map[*prometheus.Desc]bool)
+	for desc := range ch {
+		descs[desc] = true
+	}
+	return descs
+}
@@ -0,0 +1,197 @@
+package trino
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// DefaultAuthEntry is the auth file entry of the clusters not listed
+const DefaultAuthEntry = "default"
+
+// Credentials authenticate the exporter on a cluster: the username is sent as trino user, the password
+// is used for the web ui form login and for basic auth on the rest api. The password is never printed
+type Credentials struct {
+	Username string
+	Password string
+}
+
+func (c Credentials) user() string {
+	if c.Username == "" {
+		return exporterUser
+	}
+	return c.Username
+}
+
+func (c Credentials) String() string {
+	if c.Password == "" {
+		return c.user()
+	}
+	return fmt.Sprintf("%s:******", c.user())
+}
+
+func (c Credentials) GoString() string {
+	return fmt.Sprintf("trino.Credentials{Username: %q, Password: \"******\"}", c.Username)
+}
+
+// AuthSettings are the credentials of a cluster in the auth file, the password is read from
+// password_file or from the password_env environment variable every time the clusters are provided.
+// Clusters with jwt or oauth2 authentication use a bearer token instead, read from token_file
+// or obtained with the oauth2 client credentials flow. The tls block customizes the tls connections
+type AuthSettings struct {
+	Username     string          `yaml:"username"`
+	PasswordFile string          `yaml:"password_file,omitempty"`
+	PasswordEnv  string          `yaml:"password_env,omitempty"`
+	TokenFile    string          `yaml:"token_file,omitempty"`
+	OAuth2       *OAuth2Settings `yaml:"oauth2,omitempty"`
+	TLS          TLSSettings     `yaml:"tls,omitempty"`
+}
+
+// AuthFile maps the cluster names to their auth settings, the "default" entry applies to the clusters not listed
+type AuthFile map[string]AuthSettings
+
+func LoadAuthFile(path string) (AuthFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file AuthFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid auth file %s: %w", path, err)
+	}
+
+	for name, settings := range file {
+		if err := settings.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid auth file %s, cluster %s: %w", path, name, err)
+		}
+
+		if settings.TLS.InsecureSkipVerify {
+			logrus.Warnf("auth file %s, cluster %s: tls certificate verification disabled", path, name)
+		}
+	}
+
+	return file, nil
+}
+
+// Validate checks the auth settings without reading the secrets
+func (s AuthSettings) Validate() error {
+	if s.PasswordFile != "" && s.PasswordEnv != "" {
+		return errors.New("password_file and password_env are mutually exclusive")
+	}
+
+	password := s.PasswordFile != "" || s.PasswordEnv != ""
+	if (password && s.TokenFile != "") || (password && s.OAuth2 != nil) || (s.TokenFile != "" && s.OAuth2 != nil) {
+		return errors.New("password, token_file and oauth2 are mutually exclusive")
+	}
+
+	if s.OAuth2 != nil {
+		if err := s.OAuth2.validate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.TLS.config(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s AuthSettings) credentials() (Credentials, error) {
+	password, err := readSecret(s.PasswordFile, s.PasswordEnv)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	return Credentials{Username: s.Username, Password: password}, nil
+}
+
+func (s AuthSettings) tokenSource() oauth2.TokenSource {
+	switch {
+	case s.TokenFile != "":
+		return newFileTokenSource(s.TokenFile)
+	case s.OAuth2 != nil:
+		return newClientCredentialsTokenSource(*s.OAuth2)
+	}
+	return nil
+}
+
+// readSecret reads a secret from a file, trimming the trailing newline, or from an environment variable
+func readSecret(file string, env string) (string, error) {
+	switch {
+	case file != "":
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case env != "":
+		secret, present := os.LookupEnv(env)
+		if !present {
+			return "", fmt.Errorf("environment variable %s not set", env)
+		}
+		return secret, nil
+	}
+	return "", nil
+}
+
+// entry returns the name of the auth file entry of the cluster falling back to the default entry
+func (f AuthFile) entry(name string) (string, bool) {
+	if _, present := f[name]; present {
+		return name, true
+	}
+
+	_, present := f[DefaultAuthEntry]
+	return DefaultAuthEntry, present
+}
+
+// AuthClusterProvider applies the auth file to the clusters returned by the wrapped provider, the token
+// sources are created once per entry so that tokens are shared by the clusters using the same entry.
+// Clusters whose secrets can not be read are still provided but not collected, they report up 0
+type AuthClusterProvider struct {
+	provider     ClusterProvider
+	file         AuthFile
+	tokenSources map[string]oauth2.TokenSource
+}
+
+func NewAuthClusterProvider(provider ClusterProvider, file AuthFile) AuthClusterProvider {
+	tokenSources := make(map[string]oauth2.TokenSource)
+	for name, settings := range file {
+		if source := settings.tokenSource(); source != nil {
+			tokenSources[name] = source
+		}
+	}
+
+	return AuthClusterProvider{provider: provider, file: file, tokenSources: tokenSources}
+}
+
+func (a AuthClusterProvider) Provide() (map[string]ClusterInfo, error) {
+	clusters, err := a.provider.Provide()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]ClusterInfo, len(clusters))
+	for name, cluster := range clusters {
+		if entry, present := a.file.entry(name); present {
+			credentials, err := a.file[entry].credentials()
+			if err != nil {
+				cluster.unavailable = fmt.Errorf("credentials of auth entry %s: %w", entry, err)
+			}
+
+			cluster.Credentials = credentials
+			cluster.TokenSource = a.tokenSources[entry]
+			cluster.TLS = a.file[entry].TLS
+		}
+
+		result[name] = cluster
+	}
+
+	return result, nil
+}
@@ -0,0 +1,123 @@
+package trino
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuthClusterProvider(t *testing.T) {
+	dir := t.TempDir()
+
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600))
+	require.NoError(t, os.Setenv("TRINO_EXPORTER_TEST_PASSWORD", "env-secret"))
+	t.Cleanup(func() { _ = os.Unsetenv("TRINO_EXPORTER_TEST_PASSWORD") })
+
+	authFile := filepath.Join(dir, "auth.yaml")
+	require.NoError(t, ioutil.WriteFile(authFile, []byte(fmt.Sprintf(`
+default:
+  username: monitoring
+prod:
+  username: admin
+  password_file: %s
+dev:
+  username: dev
+  password_env: TRINO_EXPORTER_TEST_PASSWORD
+`, passwordFile)), 0600))
+
+	file, err := LoadAuthFile(authFile)
+	require.NoError(t, err)
+
+	clusters, err := NewAuthClusterProvider(staticClusterProvider{
+		"prod":    {Host: "http://prod"},
+		"dev":     {Host: "http://dev"},
+		"staging": {Host: "http://staging"},
+	}, file).Provide()
+	require.NoError(t, err)
+
+	require.Equal(t, Credentials{Username: "admin", Password: "secret"}, clusters["prod"].Credentials)
+	require.Equal(t, Credentials{Username: "dev", Password: "env-secret"}, clusters["dev"].Credentials)
+	require.Equal(t, Credentials{Username: "monitoring"}, clusters["staging"].Credentials)
+
+	require.NotContains(t, fmt.Sprintf("%v %+v %#v", clusters["prod"], clusters["prod"], clusters["prod"]), "secret")
+}
+
+func TestAuthClusterProviderUnreadableSecret(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/ui/api/stats": statsResponse,
+	})
+
+	exporter := NewExporter(NewAuthClusterProvider(staticClusterProvider{
+		"prod":    {Host: server.URL},
+		"staging": {Host: server.URL},
+	}, AuthFile{
+		"prod":    {Username: "admin", PasswordFile: filepath.Join(t.TempDir(), "missing")},
+		"staging": {Username: "monitoring"},
+	}), 1, NewCollector(NewClient()))
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(`
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="prod"} 0
+trino_cluster_up{cluster_name="staging"} 1
+`), "trino_cluster_up"))
+}
+
+func TestLoadAuthFileInvalid(t *testing.T) {
+	authFile := filepath.Join(t.TempDir(), "auth.yaml")
+	require.NoError(t, ioutil.WriteFile(authFile, []byte(`
+prod:
+  username: admin
+  password_file: /password
+  password_env: PASSWORD
+`), 0600))
+
+	_, err := LoadAuthFile(authFile)
+	require.Error(t, err)
+}
+
+func TestClientSendsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ui/login" {
+			require.NoError(t, r.ParseForm())
+			if r.PostForm.Get("username") != "admin" || r.PostForm.Get("password") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "Trino-UI-Token", Value: "token"})
+			w.WriteHeader(http.StatusSeeOther)
+			return
+		}
+
+		if r.URL.Path == "/v1/info" {
+			username, password, ok := r.BasicAuth()
+			if !ok || username != "admin" || password != "secret" || r.Header.Get("X-Trino-User") != "admin" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"nodeVersion": {"version": "360"}}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(statsResponse))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient()
+	cluster := ClusterInfo{Host: server.URL, Credentials: Credentials{Username: "admin", Password: "secret"}}
+
+	var response Response
+	require.NoError(t, client.getUIJSON(context.Background(), cluster, "/ui/api/stats", &response))
+
+	var info serverInfo
+	require.NoError(t, client.getJSON(context.Background(), cluster, "/v1/info", &info))
+}
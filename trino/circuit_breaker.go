@@ -0,0 +1,142 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sync"
+	"time"
+)
+
+const (
+	circuitClosed   = 0
+	circuitOpen     = 1
+	circuitHalfOpen = 2
+)
+
+var circuitBreakerState = prometheus.NewDesc(
+	prometheus.BuildFQName(exporterNamespace, "", "circuit_breaker_state"),
+	"State of the circuit breaker of the cluster (0 closed, 1 open, 2 half open), clusters are not collected while open.",
+	[]string{"cluster_name"}, nil,
+)
+
+// circuitBreakers stop collecting the clusters failed for failureThreshold consecutive collections: the circuit
+// stays open for openDuration, then a single probe collection (half open) closes it on success or opens it
+// again for twice the time, up to maxOpenDuration, so that dead coordinators are probed back slowly
+type circuitBreakers struct {
+	failureThreshold int
+	openDuration     time.Duration
+	maxOpenDuration  time.Duration
+
+	mutex    sync.Mutex
+	clusters map[string]*circuitBreaker
+}
+
+type circuitBreaker struct {
+	state    int
+	failures int
+	openedAt time.Time
+	openFor  time.Duration
+}
+
+func newCircuitBreakers(failureThreshold int, openDuration time.Duration, maxOpenDuration time.Duration) *circuitBreakers {
+	if maxOpenDuration < openDuration {
+		maxOpenDuration = openDuration
+	}
+
+	return &circuitBreakers{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		maxOpenDuration:  maxOpenDuration,
+		clusters:         make(map[string]*circuitBreaker),
+	}
+}
+
+func (b *circuitBreakers) breaker(name string) *circuitBreaker {
+	breaker, present := b.clusters[name]
+	if !present {
+		breaker = &circuitBreaker{}
+		b.clusters[name] = breaker
+	}
+	return breaker
+}
+
+// allow reports whether the cluster can be collected, moving open circuits to half open once their time elapsed
+func (b *circuitBreakers) allow(name string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	breaker := b.breaker(name)
+	switch breaker.state {
+	case circuitOpen:
+		if time.Since(breaker.openedAt) < breaker.openFor {
+			return false
+		}
+		breaker.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		// a probe collection is already running
+		return false
+	}
+
+	return true
+}
+
+// record updates the circuit of the cluster with the result of an allowed collection, returns the new state
+func (b *circuitBreakers) record(name string, failed bool) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	breaker := b.breaker(name)
+	if !failed {
+		breaker.state = circuitClosed
+		breaker.failures = 0
+		return breaker.state
+	}
+
+	switch breaker.state {
+	case circuitHalfOpen:
+		breaker.openFor *= 2
+		if breaker.openFor > b.maxOpenDuration {
+			breaker.openFor = b.maxOpenDuration
+		}
+		breaker.state = circuitOpen
+		breaker.openedAt = time.Now()
+	case circuitClosed:
+		breaker.failures++
+		if breaker.failures >= b.failureThreshold {
+			breaker.state = circuitOpen
+			breaker.openedAt = time.Now()
+			breaker.openFor = b.openDuration
+		}
+	}
+
+	return breaker.state
+}
+
+// abandon leaves the circuit of a collection cut off by the scrape deadline unchanged, an abandoned probe moves
+// the circuit back to open so that the next collection probes the cluster again. Returns the state
+func (b *circuitBreakers) abandon(name string) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	breaker := b.breaker(name)
+	if breaker.state == circuitHalfOpen {
+		breaker.state = circuitOpen
+	}
+
+	return breaker.state
+}
+
+func (b *circuitBreakers) state(name string) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.breaker(name).state
+}
+
+// forget evicts the circuit of a cluster no longer provided
+func (b *circuitBreakers) forget(name string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.clusters, name)
+}
@@ -0,0 +1,148 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy int32
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path == "/ui/login" {
+			http.SetCookie(w, &http.Cookie{Name: "Trino-UI-Token", Value: "token"})
+			w.WriteHeader(http.StatusSeeOther)
+			return
+		}
+		_, _ = w.Write([]byte(statsResponse))
+	}))
+	t.Cleanup(server.Close)
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewCollector(NewClient())).
+		WithCircuitBreaker(2, 100*time.Millisecond, time.Second)
+
+	state := func(expected string) {
+		t.Helper()
+		require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(`
+# HELP trino_exporter_circuit_breaker_state State of the circuit breaker of the cluster (0 closed, 1 open, 2 half open), clusters are not collected while open.
+# TYPE trino_exporter_circuit_breaker_state gauge
+trino_exporter_circuit_breaker_state{cluster_name="test"} `+expected+`
+`), "trino_exporter_circuit_breaker_state"))
+	}
+
+	state("0")
+	state("1")
+
+	atomic.StoreInt32(&requests, 0)
+	loginAttempts := testutil.ToFloat64(logins.WithLabelValues("test"))
+	state("1")
+	require.Equal(t, int32(0), atomic.LoadInt32(&requests))
+	require.Equal(t, loginAttempts, testutil.ToFloat64(logins.WithLabelValues("test")))
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(`
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="test"} 0
+`), "trino_cluster_up"))
+
+	time.Sleep(150 * time.Millisecond)
+	atomic.StoreInt32(&healthy, 1)
+	state("0")
+
+	exporter.Reload(staticClusterProvider{}, NewCollector(NewClient()))
+	testutil.CollectAndCount(exporter)
+	require.NotContains(t, exporter.breakers.clusters, "test")
+}
+
+func TestCircuitBreakerIgnoresScrapeDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		if r.URL.Path == "/ui/login" {
+			http.SetCookie(w, &http.Cookie{Name: "Trino-UI-Token", Value: "token"})
+			w.WriteHeader(http.StatusSeeOther)
+			return
+		}
+		_, _ = w.Write([]byte(statsResponse))
+	}))
+	t.Cleanup(server.Close)
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewCollector(NewClient())).
+		WithCircuitBreaker(2, time.Minute, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		testutil.CollectAndCount(exporter.WithContext(ctx))
+		cancel()
+	}
+
+	require.Equal(t, circuitClosed, exporter.breakers.state("test"))
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(`
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="test"} 1
+`), "trino_cluster_up"))
+}
+
+func TestExporterSkipsQueuedClustersAfterDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	exporter := NewExporter(staticClusterProvider{
+		"first":  {Host: server.URL},
+		"second": {Host: server.URL},
+	}, 1, NewCollector(NewClient()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// only the cluster started before the deadline reports up, the queued one is not collected at all
+	require.Equal(t, 1, testutil.CollectAndCount(exporter.WithContext(ctx), "trino_cluster_up"))
+}
+
+func TestClientRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			_ = conn.Close()
+		default:
+			_, _ = w.Write([]byte(`{"nodeVersion": {"version": "360"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cluster := ClusterInfo{Host: server.URL}
+
+	var info serverInfo
+	require.Error(t, NewClient().getJSON(context.Background(), cluster, "/v1/info", &info))
+
+	atomic.StoreInt32(&requests, 0)
+	require.NoError(t, NewClient(WithRetries(2, time.Millisecond)).getJSON(context.Background(), cluster, "/v1/info", &info))
+	require.Equal(t, "360", info.NodeVersion.Version)
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
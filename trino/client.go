@@ -0,0 +1,460 @@
+package trino
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const exporterUser = "exporter"
+
+type Client struct {
+	http         *http.Client
+	retries      int
+	retryBackoff time.Duration
+
+	mutex      sync.Mutex
+	sessions   map[string]session
+	tlsClients map[TLSSettings]tlsClient
+}
+
+// session is a web ui session, reused until it expires or the coordinator rejects it
+type session struct {
+	cookie  string
+	expires time.Time
+}
+
+func (s session) expired() bool {
+	return !s.expires.IsZero() && time.Now().After(s.expires)
+}
+
+// ClientOption customizes the client created by NewClient
+type ClientOption func(client *Client)
+
+// WithRetries retries the failed GET requests up to retries times, waiting about backoff before
+// the first retry and doubling it for each following one
+func WithRetries(retries int, backoff time.Duration) ClientOption {
+	return func(client *Client) {
+		client.retries = retries
+		client.retryBackoff = backoff
+	}
+}
+
+func NewClient(options ...ClientOption) *Client {
+	client := &Client{
+		http:       newHttpClient(http.DefaultTransport),
+		sessions:   make(map[string]session),
+		tlsClients: make(map[TLSSettings]tlsClient),
+	}
+
+	for _, option := range options {
+		option(client)
+	}
+
+	return client
+}
+
+func newHttpClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// tlsClient is the http client of tls settings along with the modification times of their files
+type tlsClient struct {
+	client   *http.Client
+	modTimes []time.Time
+}
+
+// httpClient returns the http client of the cluster tls settings, clusters with the same settings share
+// the client and its connection pool. The client is created again when the ca, cert or key files are modified
+// (eg: rotated in place), the previous client is kept while the modified files can not be loaded
+func (c *Client) httpClient(cluster ClusterInfo) (*http.Client, error) {
+	if cluster.TLS.isDefault() {
+		return c.http, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	modTimes := cluster.TLS.modTimes()
+
+	previous, present := c.tlsClients[cluster.TLS]
+	if present && sameTimes(previous.modTimes, modTimes) {
+		return previous.client, nil
+	}
+
+	config, err := cluster.TLS.config()
+	if err != nil {
+		if present {
+			logrus.Warnf("tls configuration of %s not reloaded, keeping the previous one: %s", cluster.Host, err)
+			return previous.client, nil
+		}
+		return nil, fmt.Errorf("tls configuration of %s: %w", cluster.Host, err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+
+	client := newHttpClient(transport)
+	c.tlsClients[cluster.TLS] = tlsClient{client: client, modTimes: modTimes}
+
+	if present {
+		logrus.Infof("tls configuration of %s reloaded", cluster.Host)
+		previous.client.CloseIdleConnections()
+	}
+
+	return client, nil
+}
+
+func sameTimes(a []time.Time, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// getJSON reads a coordinator rest api endpoint, eg: /v1/query
+func (c *Client) getJSON(ctx context.Context, cluster ClusterInfo, path string, v interface{}) error {
+	req, err := c.newRequest(ctx, cluster, path)
+	if err != nil {
+		return err
+	}
+
+	return c.doJSON(cluster, req, v)
+}
+
+// getUIJSON reads a web ui api endpoint, eg: /ui/api/stats, reusing the cluster session when present.
+// A rejected session (401 or redirect to the login page) is discarded and the request retried once after a new login.
+// Clusters authenticated with bearer tokens send the token instead of logging in
+func (c *Client) getUIJSON(ctx context.Context, cluster ClusterInfo, path string, v interface{}) error {
+	if cluster.TokenSource != nil {
+		return c.getJSON(ctx, cluster, path, v)
+	}
+
+	err := c.getUIJSONWithSession(ctx, cluster, path, v)
+	if !isSessionRejected(err) {
+		return err
+	}
+
+	c.invalidateSession(cluster)
+
+	return c.getUIJSONWithSession(ctx, cluster, path, v)
+}
+
+func (c *Client) getUIJSONWithSession(ctx context.Context, cluster ClusterInfo, path string, v interface{}) error {
+	session, err := c.session(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, cluster, path)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Cookie", session.cookie)
+
+	return c.doJSON(cluster, req, v)
+}
+
+// session returns the cached session of the cluster, logging in when missing or expired
+func (c *Client) session(ctx context.Context, cluster ClusterInfo) (session, error) {
+	c.mutex.Lock()
+	cached, present := c.sessions[sessionKey(cluster)]
+	c.mutex.Unlock()
+
+	if present && !cached.expired() {
+		return cached, nil
+	}
+
+	logins.WithLabelValues(clusterNameFrom(ctx)).Inc()
+
+	created, err := c.login(ctx, cluster)
+	if err != nil {
+		loginFailures.WithLabelValues(clusterNameFrom(ctx)).Inc()
+		return session{}, err
+	}
+
+	c.mutex.Lock()
+	c.sessions[sessionKey(cluster)] = created
+	c.mutex.Unlock()
+
+	return created, nil
+}
+
+func (c *Client) invalidateSession(cluster ClusterInfo) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.sessions, sessionKey(cluster))
+}
+
+func sessionKey(cluster ClusterInfo) string {
+	return fmt.Sprintf("%s@%s", cluster.Credentials.user(), cluster.Host)
+}
+
+// doJSON performs the request decoding the response body into v
+func (c *Client) doJSON(cluster ClusterInfo, req *http.Request, v interface{}) error {
+	body, err := c.do(cluster, req)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		observeRequestError(req, reasonDecode)
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, cluster ClusterInfo, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s%s", cluster.Host, path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if cluster.TokenSource != nil {
+		token, err := c.token(ctx, cluster)
+		if err != nil {
+			observeRequestError(req, reasonAuth)
+			return nil, fmt.Errorf("token for %s: %w", cluster.Host, err)
+		}
+
+		token.SetAuthHeader(req)
+
+		// the user of a token is its principal unless explicitly configured
+		if cluster.Credentials.Username != "" {
+			req.Header.Set(cluster.Flavor.userHeader(), cluster.Credentials.Username)
+		}
+
+		return req, nil
+	}
+
+	req.Header.Set(cluster.Flavor.userHeader(), cluster.Credentials.user())
+	if cluster.Credentials.Password != "" {
+		req.SetBasicAuth(cluster.Credentials.user(), cluster.Credentials.Password)
+	}
+
+	return req, nil
+}
+
+// do performs the request returning the response body, responses with status code other than 200 are errors
+func (c *Client) do(cluster ClusterInfo, req *http.Request) ([]byte, error) {
+	body, _, err := c.doWithHeader(cluster, req)
+	return body, err
+}
+
+// doWithHeader performs the request returning the response body and header. GET requests failed with
+// transient errors are retried with a jittered exponential backoff
+func (c *Client) doWithHeader(cluster ClusterInfo, req *http.Request) ([]byte, http.Header, error) {
+	for attempt := 0; ; attempt++ {
+		body, header, err := c.doOnce(cluster, req)
+		if err == nil || req.Method != http.MethodGet || attempt >= c.retries || !isRetryable(err) {
+			return body, header, err
+		}
+
+		retries.WithLabelValues(clusterNameFrom(req.Context()), endpointOf(req.URL.Path)).Inc()
+
+		select {
+		case <-time.After(c.backoff(attempt)):
+		case <-req.Context().Done():
+			return nil, nil, err
+		}
+	}
+}
+
+// backoff returns the delay before the retry following the attempt, a random delay between the half
+// and the whole of the exponential backoff
+func (c *Client) backoff(attempt int) time.Duration {
+	backoff := c.retryBackoff << uint(attempt)
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// isRetryable reports whether the error is transient: connection errors and unavailable coordinators are,
+// timeouts are not to avoid multiplying the scrape duration
+func isRetryable(err error) bool {
+	var statusErr statusCodeError
+	if errors.As(err, &statusErr) {
+		return statusErr.statusCode == http.StatusBadGateway ||
+			statusErr.statusCode == http.StatusServiceUnavailable ||
+			statusErr.statusCode == http.StatusGatewayTimeout
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	switch errorReason(err) {
+	case reasonDns, reasonConnect, reasonOther:
+		return true
+	}
+	return false
+}
+
+func (c *Client) doOnce(cluster ClusterInfo, req *http.Request) ([]byte, http.Header, error) {
+	resp, observer, err := c.roundTrip(cluster, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		observer.observeError(statusReason(resp.StatusCode))
+		return nil, nil, statusCodeError{url: req.URL.String(), statusCode: resp.StatusCode}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		observer.observeError(errorReason(err))
+		return nil, nil, err
+	}
+
+	return body, resp.Header, nil
+}
+
+// token returns the token of the cluster, the token sources requesting their tokens over http use the scrape
+// context and the http client of the cluster tls settings
+func (c *Client) token(ctx context.Context, cluster ClusterInfo) (*oauth2.Token, error) {
+	source, ok := cluster.TokenSource.(contextTokenSource)
+	if !ok {
+		return cluster.TokenSource.Token()
+	}
+
+	client, err := c.httpClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return source.tokenWithContext(ctx, client)
+}
+
+// roundTrip sends the request recording its duration, status code, connection phases and errors
+func (c *Client) roundTrip(cluster ClusterInfo, req *http.Request) (*http.Response, *requestObserver, error) {
+	// requests of skipped collections (eg: open circuit breaker) are not recorded
+	if err := req.Context().Err(); err != nil {
+		return nil, nil, err
+	}
+
+	observer := newRequestObserver(req)
+
+	client, err := c.httpClient(cluster)
+	if err != nil {
+		observer.observeError(reasonTls)
+		return nil, observer, err
+	}
+
+	resp, err := client.Do(observer.trace(req))
+	if err != nil {
+		observer.observeError(errorReason(err))
+		return nil, observer, err
+	}
+
+	observer.observeResponse(resp)
+
+	return resp, observer, nil
+}
+
+// login creates a new web ui session, the session expires with the earliest expiring cookie
+func (c *Client) login(ctx context.Context, cluster ClusterInfo) (session, error) {
+	loginUrl := fmt.Sprintf("%s%s", cluster.Host, "/ui/login")
+	const contentType = "application/x-www-form-urlencoded"
+	form := url.Values{
+		"username":     {cluster.Credentials.user()},
+		"password":     {cluster.Credentials.Password},
+		"redirectPath": {""},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", loginUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return session{}, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	resp, observer, err := c.roundTrip(cluster, req)
+	if err != nil {
+		return session{}, err
+	}
+
+	defer resp.Body.Close()
+
+	// a missing login page (eg: web-ui.enabled=false) is reported as status code error
+	if resp.StatusCode == http.StatusNotFound {
+		observer.observeError(reasonHttpStatus)
+		return session{}, statusCodeError{url: req.URL.String(), statusCode: resp.StatusCode}
+	}
+
+	cookies := resp.Cookies()
+
+	if len(cookies) == 0 {
+		observer.observeError(reasonAuth)
+		return session{}, errors.New("no Set-Cookie header present in response")
+	}
+
+	var result session
+	values := make([]string, 0, len(cookies))
+	for _, cookie := range cookies {
+		values = append(values, fmt.Sprintf("%s=%s", cookie.Name, cookie.Value))
+
+		expires := cookie.Expires
+		if cookie.MaxAge > 0 {
+			expires = time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		if !expires.IsZero() && (result.expires.IsZero() || expires.Before(result.expires)) {
+			result.expires = expires
+		}
+	}
+	result.cookie = strings.Join(values, "; ")
+
+	return result, nil
+}
+
+type statusCodeError struct {
+	url        string
+	statusCode int
+}
+
+func (e statusCodeError) Error() string {
+	return fmt.Sprintf("unexpected status code %d from %s", e.statusCode, e.url)
+}
+
+func isNotFound(err error) bool {
+	var statusErr statusCodeError
+	return errors.As(err, &statusErr) && statusErr.statusCode == http.StatusNotFound
+}
+
+// isSessionRejected reports whether the coordinator rejected the session, either with a 401
+// or redirecting to the login page
+func isSessionRejected(err error) bool {
+	var statusErr statusCodeError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	return statusErr.statusCode == http.StatusUnauthorized ||
+		(statusErr.statusCode >= http.StatusMultipleChoices && statusErr.statusCode < http.StatusBadRequest)
+}
@@ -0,0 +1,95 @@
+package trino
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClientReusesSession(t *testing.T) {
+	var generation int32
+	var loginCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := fmt.Sprintf("token-%d", atomic.LoadInt32(&generation))
+
+		if r.URL.Path == "/ui/login" {
+			atomic.AddInt32(&loginCount, 1)
+			http.SetCookie(w, &http.Cookie{Name: "Trino-UI-Token", Value: token})
+			w.WriteHeader(http.StatusSeeOther)
+			return
+		}
+
+		cookie, err := r.Cookie("Trino-UI-Token")
+		if err != nil || cookie.Value != token {
+			http.Redirect(w, r, "/ui/login.html", http.StatusSeeOther)
+			return
+		}
+
+		_, err = w.Write([]byte(statsResponse))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient()
+	cluster := ClusterInfo{Host: server.URL}
+
+	var response Response
+	require.NoError(t, client.getUIJSON(context.Background(), cluster, "/ui/api/stats", &response))
+	require.NoError(t, client.getUIJSON(context.Background(), cluster, "/ui/api/stats", &response))
+	require.Equal(t, int32(1), atomic.LoadInt32(&loginCount))
+
+	atomic.AddInt32(&generation, 1)
+
+	require.NoError(t, client.getUIJSON(context.Background(), cluster, "/ui/api/stats", &response))
+	require.Equal(t, int32(2), atomic.LoadInt32(&loginCount))
+}
+
+func TestSessionExpiresWithCookie(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "Trino-UI-Token", Value: "token", MaxAge: 60})
+		http.SetCookie(w, &http.Cookie{Name: "Other", Value: "value"})
+		w.WriteHeader(http.StatusSeeOther)
+	}))
+	t.Cleanup(server.Close)
+
+	session, err := NewClient().login(context.Background(), ClusterInfo{Host: server.URL})
+	require.NoError(t, err)
+	require.Equal(t, "Trino-UI-Token=token; Other=value", session.cookie)
+	require.False(t, session.expired())
+	require.False(t, session.expires.IsZero())
+}
+
+func TestClientCountsLogins(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ui/login" {
+			require.NoError(t, r.ParseForm())
+			if r.PostForm.Get("password") == "secret" {
+				http.SetCookie(w, &http.Cookie{Name: "Trino-UI-Token", Value: "token"})
+			}
+			w.WriteHeader(http.StatusSeeOther)
+			return
+		}
+
+		_, _ = w.Write([]byte(statsResponse))
+	}))
+	t.Cleanup(server.Close)
+
+	// the metrics are global, the assertions are on the increments to run with -count
+	loginAttempts := testutil.ToFloat64(logins.WithLabelValues("logins"))
+	failedLogins := testutil.ToFloat64(loginFailures.WithLabelValues("logins"))
+
+	ctx := withClusterName(context.Background(), "logins")
+	client := NewClient()
+
+	var response Response
+	require.NoError(t, client.getUIJSON(ctx, ClusterInfo{Host: server.URL, Credentials: Credentials{Username: "admin", Password: "secret"}}, "/ui/api/stats", &response))
+	require.Error(t, client.getUIJSON(ctx, ClusterInfo{Host: server.URL, Credentials: Credentials{Username: "intruder", Password: "wrong"}}, "/ui/api/stats", &response))
+
+	require.Equal(t, loginAttempts+2, testutil.ToFloat64(logins.WithLabelValues("logins")))
+	require.Equal(t, failedLogins+1, testutil.ToFloat64(loginFailures.WithLabelValues("logins")))
+}
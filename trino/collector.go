@@ -1,86 +1,114 @@
 package trino
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
-	"io/ioutil"
-	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
+// uiRecheckInterval is how long a cluster without web ui is read from the /v1 endpoints before
+// trying the web ui again
+const uiRecheckInterval = 10 * time.Minute
+
+// versionRecheckInterval is how long the detected coordinator version, or the failure to detect it, is used
+// before detecting it again
+const versionRecheckInterval = 10 * time.Minute
+
 var namespace = "trino_cluster"
 
+// prestoNamespace is the namespace of the twin metrics exported for dashboards built on presto exporters
+var prestoNamespace = "presto_cluster"
+
 var (
-	runningQueries = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "running_queries"),
+	runningQueries = newClusterDesc(
+		"", "running_queries",
 		"Running requests of the trino cluster.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
 	)
-	blockedQueries = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "blocked_queries"),
+	blockedQueries = newClusterDesc(
+		"", "blocked_queries",
 		"Blocked queries of the trino cluster.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
 	)
-	queuedQueries = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "queued_queries"),
+	queuedQueries = newClusterDesc(
+		"", "queued_queries",
 		"Queued queries of the trino cluster.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
 	)
-	activeWorkers = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "active_workers"),
+	activeWorkers = newClusterDesc(
+		"", "active_workers",
 		"Active workers of the trino cluster.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
 	)
-	runningDrivers = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "running_drivers"),
+	runningDrivers = newClusterDesc(
+		"", "running_drivers",
 		"Running drivers of the trino cluster.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
 	)
-	reservedMemory = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "reserved_memory"),
+	reservedMemory = newClusterDesc(
+		"", "reserved_memory",
 		"Reserved memory of the trino cluster.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
 	)
-	totalInputRows = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "total_input_rows"),
+	totalInputRows = newClusterDesc(
+		"", "total_input_rows",
 		"Total input rows of the trino cluster.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
 	)
-	totalInputBytes = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "total_input_bytes"),
+	totalInputBytes = newClusterDesc(
+		"", "total_input_bytes",
 		"Total input bytes of the trino cluster.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
 	)
-	totalCpuTimeSecs = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "total_cpu_time_secs"),
+	totalCpuTimeSecs = newClusterDesc(
+		"", "total_cpu_time_secs",
 		"Total cpu time of the trino cluster.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
 	)
-	up = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "up"),
+	up = newClusterDesc(
+		"", "up",
 		"trino-exporter health check.",
-		[]string{"cluster_name"}, nil,
+		[]string{"cluster_name"},
+	)
+	statsMissingFields = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "stats_missing_fields"),
+		"Fields missing from the statistics response of the coordinator version, their metrics are not exported.",
+		[]string{"cluster_name", "version"}, nil,
 	)
 )
 
 type Collector struct {
-	client          *http.Client
-	clusterProvider ClusterProvider
+	client *Client
+	// noUI holds the time the web ui was found missing by cluster host
+	noUI *sync.Map
+	// versions holds the detectedVersion by cluster host, selecting the statistics adapter
+	versions *sync.Map
+	// missingWarned holds the missingWarning already logged
+	missingWarned *sync.Map
 }
 
-func NewCollector(clusterProvider ClusterProvider) Collector {
+type detectedVersion struct {
+	version  coordinatorVersion
+	detected time.Time
+}
+
+// missingWarning identifies the warning about the missing statistics fields of a cluster version
+type missingWarning struct {
+	host    string
+	version string
+}
+
+func NewCollector(client *Client) Collector {
 	return Collector{
-		clusterProvider: clusterProvider,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-			CheckRedirect: func(*http.Request, []*http.Request) error {
-				return http.ErrUseLastResponse
-			},
-		},
+		client:        client,
+		noUI:          &sync.Map{},
+		versions:      &sync.Map{},
+		missingWarned: &sync.Map{},
 	}
 }
 
@@ -95,95 +123,183 @@ func (c Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- totalInputBytes
 	ch <- totalCpuTimeSecs
 	ch <- up
+	ch <- statsMissingFields
 }
 
-func (c Collector) Collect(out chan<- prometheus.Metric) {
-	clusters, err := c.clusterProvider.Provide()
+func (c Collector) CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error {
+	version := c.coordinatorVersion(ctx, cluster)
+	response, missing, err := c.statisticsFromCluster(ctx, cluster, version)
+	labelValues := []string{name}
+
 	if err != nil {
-		logrus.Errorf("%s", err)
-		return
+		out <- prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 0, labelValues...)
+		return err
 	}
 
-	for name, cluster := range clusters {
+	missingFields := make(map[string]bool, len(missing))
+	for _, field := range missing {
+		missingFields[field] = true
+	}
 
-		response, err := c.statisticsFromCluster(cluster)
-		labelValues := []string{name}
+	for _, field := range statsFields {
+		if !missingFields[field.name] {
+			out <- prometheus.MustNewConstMetric(field.desc, prometheus.GaugeValue, *field.value(&response), labelValues...)
+		}
+	}
 
-		if err != nil {
-			logrus.Error(err)
-			out <- prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 0, labelValues...)
-			continue
+	out <- prometheus.MustNewConstMetric(statsMissingFields, prometheus.GaugeValue, float64(len(missing)), name, version.raw)
+	out <- prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 1, labelValues...)
+
+	return nil
+}
+
+// statisticsFromCluster reads the statistics with the adapter of the coordinator version, clusters without
+// web ui (eg: web-ui.enabled=false) are detected by the 404 of the ui endpoints and read from the /v1
+// endpoints instead
+func (c Collector) statisticsFromCluster(ctx context.Context, cluster ClusterInfo, version coordinatorVersion) (Response, []string, error) {
+	if !c.uiMissing(cluster) {
+		response, missing, err := c.readClusterStats(ctx, cluster, version)
+		if !isNotFound(err) {
+			return response, missing, err
 		}
 
-		out <- prometheus.MustNewConstMetric(runningQueries, prometheus.GaugeValue, response.RunningQueries, labelValues...)
-		out <- prometheus.MustNewConstMetric(blockedQueries, prometheus.GaugeValue, response.BlockedQueries, labelValues...)
-		out <- prometheus.MustNewConstMetric(queuedQueries, prometheus.GaugeValue, response.QueuedQueries, labelValues...)
-		out <- prometheus.MustNewConstMetric(activeWorkers, prometheus.GaugeValue, response.ActiveWorkers, labelValues...)
-		out <- prometheus.MustNewConstMetric(runningDrivers, prometheus.GaugeValue, response.RunningDrivers, labelValues...)
-		out <- prometheus.MustNewConstMetric(reservedMemory, prometheus.GaugeValue, response.ReservedMemory, labelValues...)
-		out <- prometheus.MustNewConstMetric(totalInputRows, prometheus.GaugeValue, response.TotalInputRows, labelValues...)
-		out <- prometheus.MustNewConstMetric(totalInputBytes, prometheus.GaugeValue, response.TotalInputBytes, labelValues...)
-		out <- prometheus.MustNewConstMetric(totalCpuTimeSecs, prometheus.GaugeValue, response.TotalCpuTimeSecs, labelValues...)
-		out <- prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 1, labelValues...)
+		logrus.Infof("web ui of %s not found, reading the statistics from the /v1 endpoints", cluster.Host)
+		c.noUI.Store(cluster.Host, time.Now())
 	}
+
+	response, err := c.readRestApiStats(ctx, cluster)
+	return response, nil, err
 }
 
-func (c Collector) statisticsFromCluster(cluster ClusterInfo) (Response, error) {
-	return c.readClusterStats(cluster)
+func (c Collector) uiMissing(cluster ClusterInfo) bool {
+	return uiMissing(c.noUI, cluster)
 }
 
-func (c Collector) readClusterStats(cluster ClusterInfo) (Response, error) {
-	login, err := c.login(cluster)
+// uiMissing reports whether the web ui of the cluster has been found missing in the last uiRecheckInterval,
+// noUI holds the time the web ui was found missing by cluster host
+func uiMissing(noUI *sync.Map, cluster ClusterInfo) bool {
+	since, present := noUI.Load(cluster.Host)
+	if !present {
+		return false
+	}
+
+	if time.Since(since.(time.Time)) > uiRecheckInterval {
+		noUI.Delete(cluster.Host)
+		return false
+	}
+
+	return true
+}
+
+// readClusterStats reads the statistics with the adapter of the coordinator version, the missing fields are
+// logged once per cluster and version and then only reported by trino_exporter_stats_missing_fields
+func (c Collector) readClusterStats(ctx context.Context, cluster ClusterInfo, version coordinatorVersion) (Response, []string, error) {
+	adapter := statsAdapterFor(version, cluster.Flavor)
+
+	var body json.RawMessage
+	if !adapter.uiStats {
+		if err := c.client.getJSON(ctx, cluster, "/v1/cluster", &body); err != nil {
+			return Response{}, nil, err
+		}
+	} else {
+		if err := c.client.getUIJSON(ctx, cluster, "/ui/api/stats", &body); err != nil {
+			return Response{}, nil, err
+		}
+	}
+
+	response, missing, err := decodeStats(body)
 	if err != nil {
-		return Response{}, err
+		return Response{}, nil, fmt.Errorf("%s (version %s, %s adapter)", err, version.raw, adapter.name)
 	}
 
-	apiStatsUrl := fmt.Sprintf("%s%s", cluster.Host, "/ui/api/stats")
-	req, err := http.NewRequest("GET", apiStatsUrl, nil)
+	if len(missing) > 0 {
+		if _, warned := c.missingWarned.LoadOrStore(missingWarning{host: cluster.Host, version: version.raw}, true); !warned {
+			logrus.Warnf("%s: fields %s missing from the statistics response (version %s, %s adapter)",
+				cluster.Host, strings.Join(missing, ", "), version.raw, adapter.name)
+		}
+	}
+
+	return response, missing, nil
+}
+
+// coordinatorVersion returns the cached version of the coordinator detecting it from /v1/info when missing or
+// outdated, failed detections are cached as well so that a broken /v1/info is not requested on every collection
+func (c Collector) coordinatorVersion(ctx context.Context, cluster ClusterInfo) coordinatorVersion {
+	if cached, present := c.versions.Load(cluster.Host); present {
+		if detected := cached.(detectedVersion); time.Since(detected.detected) < versionRecheckInterval {
+			return detected.version
+		}
+	}
+
+	detected := detectedVersion{version: coordinatorVersion{raw: unknownVersion}, detected: time.Now()}
+
+	info, err := c.client.info(ctx, cluster)
 	if err != nil {
-		return Response{}, err
+		logrus.Debugf("unable to detect the version of %s: %s", cluster.Host, err)
+	} else if info.NodeVersion.Version != "" {
+		detected.version = parseCoordinatorVersion(info.NodeVersion.Version)
 	}
 
-	req.Header.Set("Cookie", login)
+	c.versions.Store(cluster.Host, detected)
 
-	resp, err := c.client.Do(req)
+	return detected.version
+}
+
+// readRestApiStats computes the web ui statistics from the queries and nodes of the /v1 endpoints,
+// the same way the coordinator computes them for /ui/api/stats (ClusterStatsResource): the active
+// workers are the nodes of the failure detector not failed
+func (c Collector) readRestApiStats(ctx context.Context, cluster ClusterInfo) (Response, error) {
+	queries, err := c.client.queries(ctx, cluster)
 	if err != nil {
 		return Response{}, err
 	}
 
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+	nodes, err := c.client.nodes(ctx, cluster)
 	if err != nil {
 		return Response{}, err
 	}
 
-	var response Response
-	err = json.Unmarshal(body, &response)
+	failed, err := c.client.failedNodes(ctx, cluster)
 	if err != nil {
 		return Response{}, err
 	}
 
-	return response, nil
-}
+	failedUris := make(map[string]bool, len(failed))
+	for _, node := range failed {
+		failedUris[node.Uri] = true
+	}
 
-func (c Collector) login(cluster ClusterInfo) (string, error) {
-	loginUrl := fmt.Sprintf("%s%s", cluster.Host, "/ui/login")
-	const contentType = "application/x-www-form-urlencoded"
-	const userName = "exporter"
-	body := bytes.NewBuffer([]byte(fmt.Sprintf("username=%s&password=&redirectPath=", userName)))
-	resp, err := c.client.Post(loginUrl, contentType, body)
-	if err != nil {
-		return "", err
+	response := Response{}
+	for _, node := range nodes {
+		if !failedUris[node.Uri] {
+			response.ActiveWorkers++
+		}
 	}
 
-	cookie := resp.Header.Get("Set-Cookie")
+	for _, query := range queries {
+		switch query.State {
+		case "QUEUED":
+			response.QueuedQueries++
+		case "RUNNING":
+			if query.QueryStats.FullyBlocked {
+				response.BlockedQueries++
+			} else {
+				response.RunningQueries++
+			}
+		}
+
+		if query.State == "FINISHED" || query.State == "FAILED" {
+			continue
+		}
 
-	if cookie == "" {
-		return "", errors.New("no Set-Cookie header present in response")
+		response.TotalInputBytes += float64(query.QueryStats.RawInputDataSize)
+		response.TotalInputRows += query.QueryStats.RawInputPositions
+		response.TotalCpuTimeSecs += float64(query.QueryStats.TotalCpuTime)
+		response.ReservedMemory += float64(query.QueryStats.UserMemoryReservation)
+		response.RunningDrivers += query.QueryStats.RunningDrivers
 	}
 
-	return cookie, nil
+	return response, nil
 }
 
 type Response struct {
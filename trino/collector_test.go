@@ -0,0 +1,224 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const restApiQueryListResponse = `[
+	{"queryId": "q1", "state": "RUNNING", "queryStats": {"fullyBlocked": false, "runningDrivers": 4,
+		"rawInputDataSize": "2kB", "rawInputPositions": 100, "totalCpuTime": "1.50m", "userMemoryReservation": "1kB"}},
+	{"queryId": "q2", "state": "RUNNING", "queryStats": {"fullyBlocked": true, "runningDrivers": 1,
+		"rawInputDataSize": "1kB", "rawInputPositions": 50, "totalCpuTime": "30.00s", "userMemoryReservation": "1kB"}},
+	{"queryId": "q3", "state": "QUEUED", "queryStats": {"rawInputDataSize": "0B", "totalCpuTime": "0.00ns", "userMemoryReservation": "0B"}},
+	{"queryId": "q4", "state": "FINISHED", "queryStats": {"runningDrivers": 0,
+		"rawInputDataSize": "10GB", "rawInputPositions": 1000000, "totalCpuTime": "1.00h", "userMemoryReservation": "0B"}}
+]`
+
+func TestCollectorWithoutWebUI(t *testing.T) {
+	var uiRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/ui/"):
+			atomic.AddInt32(&uiRequests, 1)
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/v1/query":
+			_, _ = w.Write([]byte(restApiQueryListResponse))
+		case r.URL.Path == "/v1/node":
+			_, _ = w.Write([]byte(`[{"uri": "http://10.0.0.1:8080"}, {"uri": "http://10.0.0.2:8080"}, {"uri": "http://10.0.0.3:8080"}]`))
+		case r.URL.Path == "/v1/node/failed":
+			_, _ = w.Write([]byte(`[{"uri": "http://10.0.0.3:8080"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewCollector(NewClient()))
+
+	expected := `
+# HELP trino_cluster_active_workers Active workers of the trino cluster.
+# TYPE trino_cluster_active_workers gauge
+trino_cluster_active_workers{cluster_name="test"} 2
+# HELP trino_cluster_blocked_queries Blocked queries of the trino cluster.
+# TYPE trino_cluster_blocked_queries gauge
+trino_cluster_blocked_queries{cluster_name="test"} 1
+# HELP trino_cluster_queued_queries Queued queries of the trino cluster.
+# TYPE trino_cluster_queued_queries gauge
+trino_cluster_queued_queries{cluster_name="test"} 1
+# HELP trino_cluster_reserved_memory Reserved memory of the trino cluster.
+# TYPE trino_cluster_reserved_memory gauge
+trino_cluster_reserved_memory{cluster_name="test"} 2048
+# HELP trino_cluster_running_drivers Running drivers of the trino cluster.
+# TYPE trino_cluster_running_drivers gauge
+trino_cluster_running_drivers{cluster_name="test"} 5
+# HELP trino_cluster_running_queries Running requests of the trino cluster.
+# TYPE trino_cluster_running_queries gauge
+trino_cluster_running_queries{cluster_name="test"} 1
+# HELP trino_cluster_total_cpu_time_secs Total cpu time of the trino cluster.
+# TYPE trino_cluster_total_cpu_time_secs gauge
+trino_cluster_total_cpu_time_secs{cluster_name="test"} 120
+# HELP trino_cluster_total_input_bytes Total input bytes of the trino cluster.
+# TYPE trino_cluster_total_input_bytes gauge
+trino_cluster_total_input_bytes{cluster_name="test"} 3072
+# HELP trino_cluster_total_input_rows Total input rows of the trino cluster.
+# TYPE trino_cluster_total_input_rows gauge
+trino_cluster_total_input_rows{cluster_name="test"} 150
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="test"} 1
+# HELP trino_exporter_stats_missing_fields Fields missing from the statistics response of the coordinator version, their metrics are not exported.
+# TYPE trino_exporter_stats_missing_fields gauge
+trino_exporter_stats_missing_fields{cluster_name="test",version="unknown"} 0
+`
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected)))
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected)))
+	require.Equal(t, int32(1), atomic.LoadInt32(&uiRequests))
+}
+
+func TestCollectorMissingFields(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/v1/info":      `{"nodeVersion": {"version": "360-e.1"}, "coordinator": true}`,
+		"/ui/api/stats": `{"runningQueries": 2, "blockedQueries": 0, "queuedQueries": 1, "activeWorkers": 3}`,
+	})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewCollector(NewClient()))
+
+	expected := `
+# HELP trino_cluster_running_queries Running requests of the trino cluster.
+# TYPE trino_cluster_running_queries gauge
+trino_cluster_running_queries{cluster_name="test"} 2
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="test"} 1
+# HELP trino_exporter_stats_missing_fields Fields missing from the statistics response of the coordinator version, their metrics are not exported.
+# TYPE trino_exporter_stats_missing_fields gauge
+trino_exporter_stats_missing_fields{cluster_name="test",version="360-e.1"} 5
+`
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected),
+		"trino_cluster_running_queries", "trino_cluster_up", "trino_exporter_stats_missing_fields"))
+	require.Equal(t, 0, testutil.CollectAndCount(exporter, "trino_cluster_running_drivers"))
+}
+
+func TestCollectorRejectsUnexpectedShape(t *testing.T) {
+	for _, body := range []string{`[1, 2]`, `{"runningQueries": "2"}`, `{"queries": 2}`, `<html></html>`} {
+		server := newTrinoServer(t, map[string]string{"/ui/api/stats": body})
+
+		exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewCollector(NewClient()))
+
+		expected := `
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="test"} 0
+`
+		require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected)), body)
+	}
+}
+
+func TestCollectorCachesVersionDetectionFailures(t *testing.T) {
+	var infoRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/info":
+			atomic.AddInt32(&infoRequests, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/ui/login":
+			http.SetCookie(w, &http.Cookie{Name: "Trino-UI-Token", Value: "token"})
+			w.WriteHeader(http.StatusSeeOther)
+		default:
+			_, _ = w.Write([]byte(`{"runningQueries": 2}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewCollector(NewClient()))
+
+	require.Equal(t, 1, testutil.CollectAndCount(exporter, "trino_cluster_running_queries"))
+	require.Equal(t, 1, testutil.CollectAndCount(exporter, "trino_cluster_running_queries"))
+	require.Equal(t, int32(1), atomic.LoadInt32(&infoRequests))
+}
+
+func TestStatsAdapterForVersion(t *testing.T) {
+	for raw, expected := range map[string]string{"360": "trino", "413-e.3": "trino", "350": "prestosql", "0.245.1": "prestodb"} {
+		version := parseCoordinatorVersion(raw)
+		require.True(t, version.known, raw)
+		require.Equal(t, expected, statsAdapterFor(version, FlavorTrino).name, raw)
+	}
+
+	version := parseCoordinatorVersion("testversion")
+	require.False(t, version.known)
+	require.Equal(t, "trino", statsAdapterFor(version, "").name)
+	require.Equal(t, "prestodb", statsAdapterFor(version, FlavorPrestoDB).name)
+}
+
+func TestCollectorSelectsAdapterByVersion(t *testing.T) {
+	var uiRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/ui/"):
+			atomic.AddInt32(&uiRequests, 1)
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/v1/info":
+			_, _ = w.Write([]byte(`{"nodeVersion": {"version": "0.245.1"}, "coordinator": true}`))
+		case r.URL.Path == "/v1/cluster":
+			_, _ = w.Write([]byte(statsResponse))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	// the cluster is declared as trino, the detected prestodb version reads /v1/cluster without login
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewCollector(NewClient()))
+
+	expected := `
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="test"} 1
+# HELP trino_exporter_stats_missing_fields Fields missing from the statistics response of the coordinator version, their metrics are not exported.
+# TYPE trino_exporter_stats_missing_fields gauge
+trino_exporter_stats_missing_fields{cluster_name="test",version="0.245.1"} 0
+`
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected), "trino_cluster_up", "trino_exporter_stats_missing_fields"))
+	require.Equal(t, int32(0), atomic.LoadInt32(&uiRequests))
+}
+
+func TestCollectorWarnsMissingFieldsOnce(t *testing.T) {
+	hook := logtest.NewGlobal()
+	t.Cleanup(func() { logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks)) })
+
+	server := newTrinoServer(t, map[string]string{
+		"/v1/info":      `{"nodeVersion": {"version": "360"}, "coordinator": true}`,
+		"/ui/api/stats": `{"runningQueries": 2}`,
+	})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewCollector(NewClient()))
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, 1, testutil.CollectAndCount(exporter, "trino_exporter_stats_missing_fields"))
+	}
+
+	warnings := 0
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel && strings.Contains(entry.Message, "missing from the statistics response") {
+			warnings++
+		}
+	}
+	require.Equal(t, 1, warnings)
+
+	for _, entry := range hook.AllEntries() {
+		if strings.Contains(entry.Message, "missing from the statistics response") {
+			require.Contains(t, entry.Message, "(version 360, trino adapter)")
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClusterCollector collects the metrics of a single trino cluster, metrics collected before
+// an error are still exported
+type ClusterCollector interface {
+	Describe(ch chan<- *prometheus.Desc)
+	CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error
+}
+
+// Exporter runs the collectors on the clusters returned by the provider, up to parallelism clusters
+// are collected concurrently so that a slow cluster delays only its own metrics. Collectors exporting
+// metrics not known in advance (eg: federation) describe no metric and make the whole exporter unchecked.
+// The provider and the collectors are shared by the copies of the exporter and can be swapped with Reload
+type Exporter struct {
+	targets         *atomic.Value
+	parallelism     int
+	prestoNamespace bool
+	breakers        *circuitBreakers
+	known           *knownClusters
+}
+
+// exporterTargets are the provider and the collectors swapped together on reload
+type exporterTargets struct {
+	clusterProvider ClusterProvider
+	collectors      []ClusterCollector
+}
+
+func NewExporter(clusterProvider ClusterProvider, parallelism int, collectors ...ClusterCollector) Exporter {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	exporter := Exporter{
+		targets:     &atomic.Value{},
+		parallelism: parallelism,
+		known:       &knownClusters{names: make(map[string]bool)},
+	}
+	exporter.Reload(clusterProvider, collectors...)
+
+	return exporter
+}
+
+// Reload swaps atomically the provider and the collectors, the collections already running complete with the
+// previous ones
+func (e Exporter) Reload(clusterProvider ClusterProvider, collectors ...ClusterCollector) {
+	e.targets.Store(exporterTargets{clusterProvider: clusterProvider, collectors: collectors})
+}
+
+func (e Exporter) current() exporterTargets {
+	return e.targets.Load().(exporterTargets)
+}
+
+// WithPrestoNamespace exports every trino_cluster metric also in the presto_cluster namespace,
+// keeping the dashboards built on presto working during the migration
+func (e Exporter) WithPrestoNamespace() Exporter {
+	e.prestoNamespace = true
+	return e
+}
+
+// WithCircuitBreaker stops collecting the clusters whose collectors all failed for failureThreshold consecutive
+// collections, the clusters are probed again after openDuration, doubled after every failed probe up to maxOpenDuration
+func (e Exporter) WithCircuitBreaker(failureThreshold int, openDuration time.Duration, maxOpenDuration time.Duration) Exporter {
+	e.breakers = newCircuitBreakers(failureThreshold, openDuration, maxOpenDuration)
+	return e
+}
+
+func (e Exporter) Describe(ch chan<- *prometheus.Desc) {
+	descs := make([]*prometheus.Desc, 0)
+	for _, collector := range e.current().collectors {
+		collectorDescs := describe(collector)
+		if len(collectorDescs) == 0 {
+			return
+		}
+		descs = append(descs, collectorDescs...)
+	}
+
+	// up is exported by the exporter itself for the clusters not collected
+	descs = append(descs, up)
+
+	for _, desc := range descs {
+		ch <- desc
+
+		if twin, present := twinDesc(desc); present && e.prestoNamespace {
+			ch <- twin
+		}
+	}
+
+	if e.breakers != nil {
+		ch <- circuitBreakerState
+	}
+}
+
+type describer interface {
+	Describe(ch chan<- *prometheus.Desc)
+}
+
+func describe(collector describer) []*prometheus.Desc {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		collector.Describe(ch)
+		close(ch)
+	}()
+
+	descs := make([]*prometheus.Desc, 0)
+	for desc := range ch {
+		descs = append(descs, desc)
+	}
+
+	return descs
+}
+
+func (e Exporter) Collect(out chan<- prometheus.Metric) {
+	e.collect(context.Background(), out)
+}
+
+// WithContext returns a collector running the exporter collections with ctx, eg: to cancel the
+// requests still running at the scrape deadline
+func (e Exporter) WithContext(ctx context.Context) prometheus.Collector {
+	return contextExporter{exporter: e, ctx: ctx}
+}
+
+type contextExporter struct {
+	exporter Exporter
+	ctx      context.Context
+}
+
+func (c contextExporter) Describe(ch chan<- *prometheus.Desc) {
+	c.exporter.Describe(ch)
+}
+
+func (c contextExporter) Collect(out chan<- prometheus.Metric) {
+	c.exporter.collect(c.ctx, out)
+}
+
+func (e Exporter) collect(ctx context.Context, out chan<- prometheus.Metric) {
+	targets := e.current()
+
+	clusters, err := targets.clusterProvider.Provide()
+	if err != nil {
+		logrus.Errorf("%s", err)
+		return
+	}
+
+	e.track(clusters)
+
+	semaphore := make(chan struct{}, e.parallelism)
+
+	var wg sync.WaitGroup
+	for name, cluster := range clusters {
+		// the clusters still queued at the scrape deadline are not started
+		if ctx.Err() != nil {
+			logrus.Warnf("cluster %s: not collected, %s", name, ctx.Err())
+			continue
+		}
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			logrus.Warnf("cluster %s: not collected, %s", name, ctx.Err())
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, cluster ClusterInfo) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			e.collectCluster(ctx, targets.collectors, name, cluster, out)
+		}(name, cluster)
+	}
+
+	wg.Wait()
+}
+
+// collectCluster runs the collectors on the cluster, returns false if any of them failed. The collectors are not
+// run on the clusters unavailable or with an open circuit, only up (0) and the state of the circuit are exported.
+// A collection cut off by the scrape deadline is not recorded by the circuit breaker, only the timeout of the
+// cluster counts as a failure
+func (e Exporter) collectCluster(ctx context.Context, collectors []ClusterCollector, name string, cluster ClusterInfo, out chan<- prometheus.Metric) bool {
+	scrapeCtx := ctx
+
+	start := time.Now()
+	defer func() { clusterScrapeDuration.WithLabelValues(name).Observe(time.Since(start).Seconds()) }()
+
+	if len(cluster.Labels) > 0 {
+		labels := labelPairs(cluster.Labels)
+
+		var wait func()
+		out, wait = relay(out, func(metric prometheus.Metric, out chan<- prometheus.Metric) {
+			out <- labelledMetric{Metric: metric, labels: labels}
+		})
+		defer wait()
+	}
+
+	if e.prestoNamespace {
+		var wait func()
+		out, wait = relay(out, func(metric prometheus.Metric, out chan<- prometheus.Metric) {
+			out <- metric
+			if twin, present := twinDesc(metric.Desc()); present {
+				out <- twinMetric{Metric: metric, desc: twin}
+			}
+		})
+		defer wait()
+	}
+
+	if cluster.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cluster.Timeout)
+		defer cancel()
+	}
+
+	if cluster.unavailable != nil {
+		logrus.Errorf("cluster %s: not collected, %s", name, cluster.unavailable)
+
+		out <- prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 0, name)
+		return false
+	}
+
+	if e.breakers != nil && !e.breakers.allow(name) {
+		logrus.Debugf("cluster %s: circuit open, collection skipped", name)
+
+		out <- prometheus.MustNewConstMetric(up, prometheus.GaugeValue, 0, name)
+		out <- prometheus.MustNewConstMetric(circuitBreakerState, prometheus.GaugeValue, float64(e.breakers.state(name)), name)
+		return false
+	}
+
+	ctx = withQueryCache(withClusterName(ctx, name))
+	failures := 0
+	for _, collector := range collectors {
+		if err := collector.CollectCluster(ctx, name, cluster, out); err != nil {
+			logrus.Errorf("cluster %s: %s", name, err)
+			failures++
+		}
+	}
+
+	if e.breakers != nil {
+		var state int
+		if scrapeCtx.Err() != nil {
+			state = e.breakers.abandon(name)
+		} else {
+			state = e.breakers.record(name, len(collectors) > 0 && failures == len(collectors))
+		}
+		out <- prometheus.MustNewConstMetric(circuitBreakerState, prometheus.GaugeValue, float64(state), name)
+	}
+
+	return failures == 0
+}
+
+// knownClusters are the names of the clusters returned by the last provision
+type knownClusters struct {
+	mutex sync.Mutex
+	names map[string]bool
+}
+
+// track records the provided clusters and forgets the state kept for the clusters no longer provided,
+// eg: the series of the exporter metrics of a removed cluster are deleted instead of being exported forever
+func (e Exporter) track(clusters map[string]ClusterInfo) {
+	e.known.mutex.Lock()
+	defer e.known.mutex.Unlock()
+
+	for name := range e.known.names {
+		if _, present := clusters[name]; !present {
+			e.forget(name)
+			delete(e.known.names, name)
+		}
+	}
+
+	for name := range clusters {
+		e.known.names[name] = true
+	}
+}
+
+// forgetter is a collector keeping state by cluster, eg: the last uptime to detect the restarts
+type forgetter interface {
+	forget(name string)
+}
+
+func (e Exporter) forget(name string) {
+	logrus.Debugf("cluster %s: no longer provided, forgetting its state", name)
+	deleteClusterSeries(name)
+
+	for _, collector := range e.current().collectors {
+		if forgetter, ok := collector.(forgetter); ok {
+			forgetter.forget(name)
+		}
+	}
+
+	if e.breakers != nil {
+		e.breakers.forget(name)
+	}
+}
+
+// relay returns a channel forwarding the metrics to out with forward, wait closes the channel and waits
+// for the forwarding of the metrics already sent
+func relay(out chan<- prometheus.Metric, forward func(metric prometheus.Metric, out chan<- prometheus.Metric)) (chan<- prometheus.Metric, func()) {
+	relayed := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for metric := range relayed {
+			forward(metric, out)
+		}
+		close(done)
+	}()
+
+	return relayed, func() {
+		close(relayed)
+		<-done
+	}
+}
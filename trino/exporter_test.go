@@ -0,0 +1,171 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const statsResponse = `{"runningQueries": 2, "blockedQueries": 0, "queuedQueries": 1, "activeWorkers": 3, "runningDrivers": 10,
+	"reservedMemory": 1024, "totalInputRows": 100, "totalInputBytes": 2048, "totalCpuTimeSecs": 12}`
+
+func TestExporterCollectsClustersConcurrently(t *testing.T) {
+	healthy := newTrinoServer(t, map[string]string{"/ui/api/stats": statsResponse})
+
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(hung.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := NewClient()
+	client.http.Timeout = 300 * time.Millisecond
+
+	exporter := NewExporter(staticClusterProvider{
+		"healthy": {Host: healthy.URL},
+		"hung-1":  {Host: hung.URL},
+		"hung-2":  {Host: hung.URL},
+	}, 3, NewCollector(client))
+
+	expected := `
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="healthy"} 1
+trino_cluster_up{cluster_name="hung-1"} 0
+trino_cluster_up{cluster_name="hung-2"} 0
+`
+
+	start := time.Now()
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected), "trino_cluster_up"))
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestExporterAttachesClusterLabels(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{"/ui/api/stats": statsResponse})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewExporter(staticClusterProvider{
+		"test": {Host: server.URL, Labels: map[string]string{"team": "data", "env": "prod", "cluster_name": "ignored"}},
+	}, 1, NewCollector(NewClient())).WithPrestoNamespace())
+
+	expected := `
+# HELP presto_cluster_up trino-exporter health check.
+# TYPE presto_cluster_up gauge
+presto_cluster_up{cluster_name="test",env="prod",team="data"} 1
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="test",env="prod",team="data"} 1
+`
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "trino_cluster_up", "presto_cluster_up"))
+}
+
+func TestExporterClusterTimeout(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(hung.Close)
+	t.Cleanup(func() { close(release) })
+
+	exporter := NewExporter(staticClusterProvider{
+		"hung": {Host: hung.URL, Timeout: 200 * time.Millisecond},
+	}, 1, NewCollector(NewClient()))
+
+	expected := `
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="hung"} 0
+`
+
+	start := time.Now()
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected), "trino_cluster_up"))
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestExporterReload(t *testing.T) {
+	healthy := newTrinoServer(t, map[string]string{"/ui/api/stats": statsResponse})
+
+	exporter := NewExporter(staticClusterProvider{"before": {Host: healthy.URL}}, 1, NewCollector(NewClient()))
+	copied := exporter.WithPrestoNamespace()
+
+	exporter.Reload(staticClusterProvider{"after": {Host: healthy.URL}}, NewCollector(NewClient()))
+
+	expected := `
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="after"} 1
+`
+
+	require.NoError(t, testutil.CollectAndCompare(copied, strings.NewReader(expected), "trino_cluster_up"))
+}
+
+func TestExporterReadsQueriesOncePerCollection(t *testing.T) {
+	var queryRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/query":
+			atomic.AddInt32(&queryRequests, 1)
+			_, _ = w.Write([]byte(restApiQueryListResponse))
+		case "/v1/node", "/v1/node/failed":
+			_, _ = w.Write([]byte(`[]`))
+		case "/v1/cluster/memory":
+			_, _ = w.Write([]byte(`{"memoryPoolInfo": {"maxBytes": 4096, "reservedBytes": 1024}}`))
+		case "/v1/resourceGroupState/global":
+			_, _ = w.Write([]byte(`{"id": ["global"], "state": "CAN_RUN"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient()
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewCollector(client),
+		NewQueryCollector(client, 20, 10), NewMemoryCollector(client), NewResourceGroupCollector(client, []string{"global"}))
+
+	require.Equal(t, 1, testutil.CollectAndCount(exporter, "trino_cluster_up"))
+	require.Equal(t, int32(1), atomic.LoadInt32(&queryRequests))
+
+	require.Equal(t, 1, testutil.CollectAndCount(exporter, "trino_cluster_up"))
+	require.Equal(t, int32(2), atomic.LoadInt32(&queryRequests))
+}
+
+func TestSanitizeLabelName(t *testing.T) {
+	require.Equal(t, "label_app_kubernetes_io_team", SanitizeLabelName("label_", "app.kubernetes.io/team"))
+	require.Equal(t, "tag_cost_center", SanitizeLabelName("tag_", "cost-center"))
+	require.NoError(t, ValidateLabels(map[string]string{SanitizeLabelName("tag_", "aws:cloudformation:stack-name"): "stack"}))
+}
+
+func TestExporterClustersWithDifferentLabels(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{"/ui/api/stats": statsResponse})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewExporter(staticClusterProvider{
+		"static":     {Host: server.URL, Labels: map[string]string{"team": "data"}},
+		"discovered": {Host: server.URL, Labels: map[string]string{"provider": "kubernetes", "namespace": "trino"}},
+	}, 2, NewCollector(NewClient())))
+
+	expected := `
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="discovered",namespace="trino",provider="kubernetes"} 1
+trino_cluster_up{cluster_name="static",team="data"} 1
+`
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "trino_cluster_up"))
+}
+
+func TestLabelCollisions(t *testing.T) {
+	require.Equal(t, []string{"pool", "user"}, LabelCollisions(map[string]string{"user": "a", "team": "data", "pool": "b"}))
+	require.Empty(t, LabelCollisions(map[string]string{"team": "data"}))
+
+	require.Equal(t, map[string][]string{"tag_cost_center": {"cost-center", "cost.center"}},
+		SanitizedLabelCollisions("tag_", []string{"cost-center", "team", "cost.center"}))
+}
@@ -0,0 +1,183 @@
+package trino
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+	"mime"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	clusterNameLabel         = "cluster_name"
+	exportedClusterNameLabel = "exported_cluster_name"
+)
+
+// FederationCollector pulls the native metrics endpoint of the coordinator (/metrics) and re-exposes its
+// samples adding the cluster_name label, a native cluster_name label is renamed to exported_cluster_name.
+// As the native metrics are not known in advance the collector is registered as unchecked. The help and
+// type of a family are the ones first seen on any cluster, so that clusters running different versions
+// export consistent families: samples of a family with a different type are dropped
+type FederationCollector struct {
+	client *Client
+	// families holds the familyShape first seen by family name
+	families *sync.Map
+}
+
+type familyShape struct {
+	help       string
+	metricType dto.MetricType
+}
+
+func NewFederationCollector(client *Client) FederationCollector {
+	return FederationCollector{
+		client:   client,
+		families: &sync.Map{},
+	}
+}
+
+func (c FederationCollector) Describe(chan<- *prometheus.Desc) {
+}
+
+func (c FederationCollector) CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error {
+	families, err := c.client.nativeMetrics(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(families))
+	for familyName := range families {
+		names = append(names, familyName)
+	}
+	sort.Strings(names)
+
+	for _, familyName := range names {
+		family := families[familyName]
+
+		help := family.GetHelp()
+		if help == "" {
+			help = family.GetName()
+		}
+
+		pinned, _ := c.families.LoadOrStore(familyName, familyShape{help: help, metricType: family.GetType()})
+		shape := pinned.(familyShape)
+		if shape.metricType != family.GetType() {
+			logrus.Warnf("native metric %s of cluster %s dropped: type %s, %s on the other clusters", familyName, name, family.GetType(), shape.metricType)
+			continue
+		}
+
+		for _, metric := range family.Metric {
+			relabeled, err := relabelMetric(family, shape.help, metric, name)
+			if err != nil {
+				logrus.Debugf("unable to federate metric %s of cluster %s: %s", familyName, name, err)
+				continue
+			}
+			out <- relabeled
+		}
+	}
+
+	return nil
+}
+
+func relabelMetric(family *dto.MetricFamily, help string, metric *dto.Metric, clusterName string) (prometheus.Metric, error) {
+	labelNames := make([]string, 0, len(metric.Label)+1)
+	labelValues := make([]string, 0, len(metric.Label)+1)
+	for _, label := range metric.Label {
+		labelName := label.GetName()
+		if labelName == clusterNameLabel {
+			labelName = exportedClusterNameLabel
+		}
+		labelNames = append(labelNames, labelName)
+		labelValues = append(labelValues, label.GetValue())
+	}
+	labelNames = append(labelNames, clusterNameLabel)
+	labelValues = append(labelValues, clusterName)
+
+	desc := prometheus.NewDesc(family.GetName(), help, labelNames, nil)
+
+	var result prometheus.Metric
+	var err error
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		result, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, metric.GetCounter().GetValue(), labelValues...)
+	case dto.MetricType_GAUGE:
+		result, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, metric.GetGauge().GetValue(), labelValues...)
+	case dto.MetricType_UNTYPED:
+		result, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, metric.GetUntyped().GetValue(), labelValues...)
+	case dto.MetricType_SUMMARY:
+		quantiles := make(map[float64]float64, len(metric.GetSummary().GetQuantile()))
+		for _, quantile := range metric.GetSummary().GetQuantile() {
+			quantiles[quantile.GetQuantile()] = quantile.GetValue()
+		}
+		result, err = prometheus.NewConstSummary(desc, metric.GetSummary().GetSampleCount(), metric.GetSummary().GetSampleSum(), quantiles, labelValues...)
+	case dto.MetricType_HISTOGRAM:
+		buckets := make(map[float64]uint64, len(metric.GetHistogram().GetBucket()))
+		for _, bucket := range metric.GetHistogram().GetBucket() {
+			buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+		}
+		result, err = prometheus.NewConstHistogram(desc, metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum(), buckets, labelValues...)
+	default:
+		err = fmt.Errorf("unsupported metric type %s", family.GetType())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if metric.TimestampMs != nil {
+		result = prometheus.NewMetricWithTimestamp(time.Unix(0, metric.GetTimestampMs()*int64(time.Millisecond)), result)
+	}
+
+	return result, nil
+}
+
+// nativeMetricsAccept prefers openmetrics, served by the coordinators, falling back to the text format
+var nativeMetricsAccept = string(expfmt.FmtOpenMetrics) + "," + string(expfmt.FmtText) + ";q=0.5"
+
+// nativeMetrics reads the coordinator metrics endpoint in the openmetrics or in the prometheus text format,
+// openmetrics is converted to the text format before parsing
+func (c *Client) nativeMetrics(ctx context.Context, cluster ClusterInfo) (map[string]*dto.MetricFamily, error) {
+	req, err := c.newRequest(ctx, cluster, "/metrics")
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", nativeMetricsAccept)
+
+	body, header, err := c.doWithHeader(cluster, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if isOpenMetrics(header) {
+		if body, err = openMetricsToText(body); err != nil {
+			observeRequestError(req, reasonDecode)
+			return nil, fmt.Errorf("invalid metrics from %s: %w", req.URL, err)
+		}
+	} else if contentType := header.Get("Content-Type"); contentType != "" && expfmt.ResponseFormat(header) != expfmt.FmtText {
+		observeRequestError(req, reasonDecode)
+		return nil, fmt.Errorf("unsupported metrics format %s from %s", contentType, req.URL)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		observeRequestError(req, reasonDecode)
+		return nil, fmt.Errorf("invalid metrics from %s: %w", req.URL, err)
+	}
+
+	return families, nil
+}
+
+func isOpenMetrics(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == expfmt.OpenMetricsType
+}
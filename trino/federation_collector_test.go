@@ -0,0 +1,165 @@
+package trino
+
+import (
+	"bytes"
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const nativeMetricsResponse = `# TYPE trino_execution_running_queries gauge
+trino_execution_running_queries 3.0
+# TYPE trino_memory_pool_bytes untyped
+trino_memory_pool_bytes{cluster_name="native",pool="general"} 1024.0
+`
+
+func TestFederationCollector(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{"/metrics": nativeMetricsResponse})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewFederationCollector(NewClient())))
+
+	expected := `
+# HELP trino_execution_running_queries trino_execution_running_queries
+# TYPE trino_execution_running_queries gauge
+trino_execution_running_queries{cluster_name="test"} 3
+# HELP trino_memory_pool_bytes trino_memory_pool_bytes
+# TYPE trino_memory_pool_bytes untyped
+trino_memory_pool_bytes{cluster_name="test",exported_cluster_name="native",pool="general"} 1024
+`
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+}
+
+func TestFederationCollectorMixedVersions(t *testing.T) {
+	old := newTrinoServer(t, map[string]string{"/metrics": `# HELP trino_running_queries Running queries
+# TYPE trino_running_queries gauge
+trino_running_queries 3
+# TYPE trino_failed_queries gauge
+trino_failed_queries 1
+`})
+	upgraded := newTrinoServer(t, map[string]string{"/metrics": `# HELP trino_running_queries Number of running queries
+# TYPE trino_running_queries gauge
+trino_running_queries 5
+# TYPE trino_failed_queries counter
+trino_failed_queries 2
+`})
+
+	collector := NewFederationCollector(NewClient())
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewExporter(staticClusterProvider{"old": {Host: old.URL}}, 1, collector))
+	_, err := registry.Gather()
+	require.NoError(t, err)
+
+	registry = prometheus.NewRegistry()
+	registry.MustRegister(NewExporter(staticClusterProvider{"old": {Host: old.URL}, "upgraded": {Host: upgraded.URL}}, 2, collector))
+
+	expected := `
+# HELP trino_failed_queries trino_failed_queries
+# TYPE trino_failed_queries gauge
+trino_failed_queries{cluster_name="old"} 1
+# HELP trino_running_queries Running queries
+# TYPE trino_running_queries gauge
+trino_running_queries{cluster_name="old"} 3
+trino_running_queries{cluster_name="upgraded"} 5
+`
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+}
+
+const nativeOpenMetricsResponse = `# TYPE trino_execution_QueryManager_RunningQueries gauge
+# HELP trino_execution_QueryManager_RunningQueries Running queries of the \"coordinator\"
+trino_execution_QueryManager_RunningQueries 3.0
+# TYPE trino_execution_QueryManager_FailedQueries counter
+# UNIT trino_execution_QueryManager_FailedQueries queries
+# HELP trino_execution_QueryManager_FailedQueries Failed queries
+trino_execution_QueryManager_FailedQueries_total{reason="user#1 {error}"} 2.0 # {trace_id="abc"} 1.0 1520879607.789
+trino_execution_QueryManager_FailedQueries_created{reason="user#1 {error}"} 1520879600.0
+# TYPE trino_memory_heap_bytes unknown
+trino_memory_heap_bytes{pool="general"} 1024 1520879607.789
+# TYPE trino_build info
+trino_build_info{version="360"} 1
+# TYPE trino_execution_QueryManager_ExecutionTime summary
+trino_execution_QueryManager_ExecutionTime{quantile="0.5"} 1.5
+trino_execution_QueryManager_ExecutionTime_sum 12.0
+trino_execution_QueryManager_ExecutionTime_count 8
+trino_execution_QueryManager_ExecutionTime_created 1520879600.0
+# EOF
+`
+
+func TestFederationCollectorOpenMetrics(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		_, _ = w.Write([]byte(nativeOpenMetricsResponse))
+	}))
+	t.Cleanup(server.Close)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewFederationCollector(NewClient())))
+
+	expected := `
+# HELP trino_build_info trino_build_info
+# TYPE trino_build_info gauge
+trino_build_info{cluster_name="test",version="360"} 1
+# HELP trino_execution_QueryManager_ExecutionTime trino_execution_QueryManager_ExecutionTime
+# TYPE trino_execution_QueryManager_ExecutionTime summary
+trino_execution_QueryManager_ExecutionTime{cluster_name="test",quantile="0.5"} 1.5
+trino_execution_QueryManager_ExecutionTime_sum{cluster_name="test"} 12
+trino_execution_QueryManager_ExecutionTime_count{cluster_name="test"} 8
+# HELP trino_execution_QueryManager_FailedQueries_total Failed queries
+# TYPE trino_execution_QueryManager_FailedQueries_total counter
+trino_execution_QueryManager_FailedQueries_total{cluster_name="test",reason="user#1 {error}"} 2
+# HELP trino_execution_QueryManager_RunningQueries Running queries of the "coordinator"
+# TYPE trino_execution_QueryManager_RunningQueries gauge
+trino_execution_QueryManager_RunningQueries{cluster_name="test"} 3
+# HELP trino_memory_heap_bytes trino_memory_heap_bytes
+# TYPE trino_memory_heap_bytes untyped
+trino_memory_heap_bytes{cluster_name="test",pool="general"} 1024 1520879607789
+`
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+	require.True(t, strings.HasPrefix(accept, "application/openmetrics-text"), accept)
+}
+
+func TestFederationCollectorInvalidFormats(t *testing.T) {
+	for contentType, body := range map[string]string{
+		"application/openmetrics-text; version=1.0.0": "# TYPE trino_running_queries gauge\ntrino_running_queries 3\n",
+		"application/json": `{"trino_running_queries": 3}`,
+	} {
+		contentType, body := contentType, body
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(server.Close)
+
+		_, err := NewClient().nativeMetrics(withClusterName(context.Background(), "test"), ClusterInfo{Host: server.URL})
+		require.Error(t, err, contentType)
+	}
+}
+
+func TestOpenMetricsHelpEscapes(t *testing.T) {
+	text, err := openMetricsToText([]byte(`# HELP trino_queries Queries of the \"adhoc\" group\nby user, path C:\\trino
+# TYPE trino_queries gauge
+trino_queries 3
+# EOF
+`))
+	require.NoError(t, err)
+	require.Equal(t, `# HELP trino_queries Queries of the "adhoc" group\nby user, path C:\\trino
+# TYPE trino_queries gauge
+trino_queries 3
+`, string(text))
+
+	families, err := new(expfmt.TextParser).TextToMetricFamilies(bytes.NewReader(text))
+	require.NoError(t, err)
+	require.Equal(t, "Queries of the \"adhoc\" group\nby user, path C:\\trino", families["trino_queries"].GetHelp())
+}
@@ -0,0 +1,84 @@
+package trino
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"strings"
+	"sync"
+)
+
+// Flavor is the distribution run by a cluster, it selects the user header, the statistics endpoint
+// and the mbean domain. The zero value is trino
+type Flavor string
+
+const (
+	FlavorTrino     Flavor = "trino"
+	FlavorPrestoSQL Flavor = "prestosql"
+	FlavorPrestoDB  Flavor = "prestodb"
+)
+
+func ParseFlavor(value string) (Flavor, error) {
+	switch flavor := Flavor(strings.ToLower(value)); flavor {
+	case "", FlavorTrino:
+		return FlavorTrino, nil
+	case FlavorPrestoSQL, FlavorPrestoDB:
+		return flavor, nil
+	default:
+		return "", fmt.Errorf("unknown flavor %s, expected one of trino, prestosql, prestodb", value)
+	}
+}
+
+func (f Flavor) isPresto() bool {
+	return f == FlavorPrestoSQL || f == FlavorPrestoDB
+}
+
+func (f Flavor) userHeader() string {
+	if f.isPresto() {
+		return "X-Presto-User"
+	}
+	return "X-Trino-User"
+}
+
+// hasUILogin reports whether the statistics are read from the web ui api behind the form login,
+// prestodb exposes them without login on /v1/cluster
+func (f Flavor) hasUILogin() bool {
+	return f != FlavorPrestoDB
+}
+
+// mbean translates the object name of a trino mbean to the domain of the flavor, eg: trino.execution to presto.execution
+func (f Flavor) mbean(objectName string) string {
+	if f.isPresto() && strings.HasPrefix(objectName, "trino.") {
+		return "presto." + strings.TrimPrefix(objectName, "trino.")
+	}
+	return objectName
+}
+
+// descTwins maps the descs of the trino_cluster namespace to their presto_cluster twins
+var descTwins sync.Map
+
+// newClusterDesc creates a desc in the trino_cluster namespace along with its presto_cluster twin
+func newClusterDesc(subsystem string, name string, help string, variableLabels []string) *prometheus.Desc {
+	desc := prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, variableLabels, nil)
+	twin := prometheus.NewDesc(prometheus.BuildFQName(prestoNamespace, subsystem, name), help, variableLabels, nil)
+	descTwins.Store(desc, twin)
+
+	return desc
+}
+
+func twinDesc(desc *prometheus.Desc) (*prometheus.Desc, bool) {
+	twin, present := descTwins.Load(desc)
+	if !present {
+		return nil, false
+	}
+	return twin.(*prometheus.Desc), true
+}
+
+// twinMetric is a metric exported with the desc of its presto_cluster twin
+type twinMetric struct {
+	prometheus.Metric
+	desc *prometheus.Desc
+}
+
+func (m twinMetric) Desc() *prometheus.Desc {
+	return m.desc
+}
@@ -0,0 +1,75 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrestoDBFlavor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Presto-User") != exporterUser || r.Header.Get("X-Trino-User") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/cluster":
+			_, _ = w.Write([]byte(statsResponse))
+		case "/v1/jmx/mbean/presto.execution:name=QueryManager":
+			_, _ = w.Write([]byte(`{"objectName": "presto.execution:name=QueryManager", "attributes": [{"name": "FailedQueries.TotalCount", "value": 3}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	jmxCollector, err := NewJmxCollector(NewClient(), []JmxMetric{
+		{MBean: "trino.execution:name=QueryManager", Attribute: "FailedQueries.TotalCount", Name: "failed_queries_total", Help: "Failed queries.", Type: JmxCounter},
+	})
+	require.NoError(t, err)
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL, Flavor: FlavorPrestoDB}}, 1,
+		NewCollector(NewClient()), jmxCollector).WithPrestoNamespace()
+
+	expected := `
+# HELP presto_cluster_jmx_failed_queries_total Failed queries.
+# TYPE presto_cluster_jmx_failed_queries_total counter
+presto_cluster_jmx_failed_queries_total{cluster_name="test"} 3
+# HELP presto_cluster_running_queries Running requests of the trino cluster.
+# TYPE presto_cluster_running_queries gauge
+presto_cluster_running_queries{cluster_name="test"} 2
+# HELP presto_cluster_up trino-exporter health check.
+# TYPE presto_cluster_up gauge
+presto_cluster_up{cluster_name="test"} 1
+# HELP trino_cluster_jmx_failed_queries_total Failed queries.
+# TYPE trino_cluster_jmx_failed_queries_total counter
+trino_cluster_jmx_failed_queries_total{cluster_name="test"} 3
+# HELP trino_cluster_running_queries Running requests of the trino cluster.
+# TYPE trino_cluster_running_queries gauge
+trino_cluster_running_queries{cluster_name="test"} 2
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="test"} 1
+`
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected),
+		"presto_cluster_jmx_failed_queries_total", "presto_cluster_running_queries", "presto_cluster_up",
+		"trino_cluster_jmx_failed_queries_total", "trino_cluster_running_queries", "trino_cluster_up"))
+}
+
+func TestParseFlavor(t *testing.T) {
+	flavor, err := ParseFlavor("")
+	require.NoError(t, err)
+	require.Equal(t, FlavorTrino, flavor)
+
+	flavor, err = ParseFlavor("PrestoSQL")
+	require.NoError(t, err)
+	require.Equal(t, FlavorPrestoSQL, flavor)
+
+	_, err = ParseFlavor("hive")
+	require.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// ScrapeHandler serves the metrics collecting the exporter within the scrape timeout sent by prometheus, less
+// timeoutOffset to leave time for the response. The requests still running at the deadline are canceled and
+// the metrics gathered until then are served. The metrics of gatherer are served along with the exporter ones
+type ScrapeHandler struct {
+	gatherer      prometheus.Gatherer
+	exporter      Exporter
+	timeoutOffset time.Duration
+	opts          promhttp.HandlerOpts
+}
+
+func NewScrapeHandler(gatherer prometheus.Gatherer, exporter Exporter, timeoutOffset time.Duration, opts promhttp.HandlerOpts) ScrapeHandler {
+	return ScrapeHandler{
+		gatherer:      gatherer,
+		exporter:      exporter,
+		timeoutOffset: timeoutOffset,
+		opts:          opts,
+	}
+}
+
+func (h ScrapeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := h.scrapeContext(r)
+	defer cancel()
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(h.exporter.WithContext(ctx)); err != nil {
+		logrus.Errorf("%s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	promhttp.HandlerFor(prometheus.Gatherers{h.gatherer, registry}, h.opts).ServeHTTP(w, r)
+}
+
+// scrapeContext returns the context of the scrape, with a deadline when prometheus sent the scrape timeout
+func (h ScrapeHandler) scrapeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	header := r.Header.Get(scrapeTimeoutHeader)
+	if header == "" {
+		return context.WithCancel(r.Context())
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		logrus.Warnf("invalid %s header: %s", scrapeTimeoutHeader, header)
+		return context.WithCancel(r.Context())
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > h.timeoutOffset {
+		timeout -= h.timeoutOffset
+	}
+
+	return context.WithTimeout(r.Context(), timeout)
+}
@@ -0,0 +1,47 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScrapeHandlerHonorsScrapeTimeout(t *testing.T) {
+	healthy := newTrinoServer(t, map[string]string{"/ui/api/stats": statsResponse})
+
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(hung.Close)
+	t.Cleanup(func() { close(release) })
+
+	exporter := NewExporter(staticClusterProvider{
+		"healthy": {Host: healthy.URL},
+		"hung":    {Host: hung.URL},
+	}, 2, NewCollector(NewClient()))
+
+	handler := NewScrapeHandler(prometheus.NewRegistry(), exporter, 100*time.Millisecond, promhttp.HandlerOpts{})
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set(scrapeTimeoutHeader, "0.3")
+	recorder := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(recorder, req)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	body, err := ioutil.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, string(body), `trino_cluster_up{cluster_name="healthy"} 1`)
+	require.Contains(t, string(body), `trino_cluster_up{cluster_name="hung"} 0`)
+}
@@ -0,0 +1,107 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"sync"
+)
+
+var (
+	clusterInfo = newClusterDesc(
+		"", "info",
+		"Version, environment and state of the trino cluster coordinator.",
+		[]string{"cluster_name", "version", "environment", "coordinator", "starting"},
+	)
+	uptimeSeconds = newClusterDesc(
+		"", "uptime_seconds",
+		"Uptime of the trino cluster coordinator.",
+		[]string{"cluster_name"},
+	)
+	restarts = newClusterDesc(
+		"", "restarts_total",
+		"Restarts of the trino cluster coordinator detected by the exporter.",
+		[]string{"cluster_name"},
+	)
+)
+
+// InfoCollector exports the coordinator info (/v1/info), restarts are detected when the uptime decreases
+// between two collections. The uptimes and the restarts of the clusters no longer provided are forgotten
+type InfoCollector struct {
+	client *Client
+
+	mutex    sync.Mutex
+	uptimes  map[string]float64
+	restarts map[string]float64
+}
+
+func NewInfoCollector(client *Client) *InfoCollector {
+	return &InfoCollector{
+		client:   client,
+		uptimes:  make(map[string]float64),
+		restarts: make(map[string]float64),
+	}
+}
+
+func (c *InfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clusterInfo
+	ch <- uptimeSeconds
+	ch <- restarts
+}
+
+func (c *InfoCollector) CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error {
+	info, err := c.client.info(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	out <- prometheus.MustNewConstMetric(clusterInfo, prometheus.GaugeValue, 1, name,
+		info.NodeVersion.Version, info.Environment, strconv.FormatBool(info.Coordinator), strconv.FormatBool(info.Starting))
+	out <- prometheus.MustNewConstMetric(uptimeSeconds, prometheus.GaugeValue, float64(info.Uptime), name)
+	out <- prometheus.MustNewConstMetric(restarts, prometheus.CounterValue, c.trackRestarts(name, float64(info.Uptime)), name)
+
+	return nil
+}
+
+// trackRestarts records the last seen uptime of the cluster returning the restarts seen so far
+func (c *InfoCollector) trackRestarts(name string, uptime float64) float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	last, present := c.uptimes[name]
+	if present && uptime < last {
+		logrus.Infof("detected restart of cluster %s", name)
+		c.restarts[name]++
+	}
+
+	c.uptimes[name] = uptime
+	return c.restarts[name]
+}
+
+func (c *InfoCollector) forget(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.uptimes, name)
+	delete(c.restarts, name)
+}
+
+func (c *Client) info(ctx context.Context, cluster ClusterInfo) (serverInfo, error) {
+	var info serverInfo
+	if err := c.getJSON(ctx, cluster, "/v1/info", &info); err != nil {
+		return serverInfo{}, err
+	}
+
+	return info, nil
+}
+
+type serverInfo struct {
+	NodeVersion struct {
+		Version string `json:"version"`
+	} `json:"nodeVersion"`
+	Environment string   `json:"environment"`
+	Coordinator bool     `json:"coordinator"`
+	Starting    bool     `json:"starting"`
+	Uptime      duration `json:"uptime"`
+}
@@ -0,0 +1,53 @@
+package trino
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestInfoCollectorRestarts(t *testing.T) {
+	uptime := atomic.Value{}
+	uptime.Store("2.00h")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"nodeVersion": {"version": "360"}, "environment": "production", "coordinator": true,
+			"starting": false, "uptime": "%s"}`, uptime.Load())
+	}))
+	t.Cleanup(server.Close)
+
+	collector := NewInfoCollector(NewClient())
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, collector)
+
+	expected := func(uptime string, restarts string) *strings.Reader {
+		return strings.NewReader(`
+# HELP trino_cluster_info Version, environment and state of the trino cluster coordinator.
+# TYPE trino_cluster_info gauge
+trino_cluster_info{cluster_name="test",coordinator="true",environment="production",starting="false",version="360"} 1
+# HELP trino_cluster_restarts_total Restarts of the trino cluster coordinator detected by the exporter.
+# TYPE trino_cluster_restarts_total counter
+trino_cluster_restarts_total{cluster_name="test"} ` + restarts + `
+# HELP trino_cluster_uptime_seconds Uptime of the trino cluster coordinator.
+# TYPE trino_cluster_uptime_seconds gauge
+trino_cluster_uptime_seconds{cluster_name="test"} ` + uptime + `
+`)
+	}
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, expected("7200", "0")))
+
+	uptime.Store("1.00m")
+	require.NoError(t, testutil.CollectAndCompare(exporter, expected("60", "1")))
+
+	uptime.Store("2.00m")
+	require.NoError(t, testutil.CollectAndCompare(exporter, expected("120", "1")))
+
+	exporter.Reload(staticClusterProvider{}, collector)
+	testutil.CollectAndCount(exporter)
+	require.NotContains(t, collector.uptimes, "test")
+	require.NotContains(t, collector.restarts, "test")
+}
@@ -0,0 +1,280 @@
+package trino
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	reasonDns        = "dns"
+	reasonConnect    = "connect"
+	reasonTimeout    = "timeout"
+	reasonTls        = "tls"
+	reasonAuth       = "auth"
+	reasonHttpStatus = "http_status"
+	reasonDecode     = "decode"
+	reasonOther      = "other"
+)
+
+var (
+	clusterScrapeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: exporterNamespace,
+		Name:      "cluster_scrape_duration_seconds",
+		Help:      "Duration of the collection of a cluster.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"cluster_name"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: exporterNamespace,
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the requests to the trino endpoints.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"cluster_name", "endpoint"})
+
+	requestPhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: exporterNamespace,
+		Name:      "request_phase_duration_seconds",
+		Help:      "Duration of the connection phases (dns, connect, tls, first_byte) of the requests to the trino endpoints.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"cluster_name", "endpoint", "phase"})
+
+	responses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: exporterNamespace,
+		Name:      "responses_total",
+		Help:      "Responses received from the trino endpoints by status code.",
+	}, []string{"cluster_name", "endpoint", "code"})
+
+	requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: exporterNamespace,
+		Name:      "request_errors_total",
+		Help:      "Failed requests to the trino endpoints by reason (dns, connect, timeout, tls, auth, http_status, decode, other).",
+	}, []string{"cluster_name", "endpoint", "reason"})
+
+	retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: exporterNamespace,
+		Name:      "request_retries_total",
+		Help:      "Requests to the trino endpoints retried after a transient error.",
+	}, []string{"cluster_name", "endpoint"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: exporterNamespace,
+		Name:      "logins_total",
+		Help:      "Web ui logins performed on the cluster.",
+	}, []string{"cluster_name"})
+
+	loginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: exporterNamespace,
+		Name:      "login_failures_total",
+		Help:      "Web ui logins failed on the cluster.",
+	}, []string{"cluster_name"})
+)
+
+// clusterVec is a metric vector labelled by cluster_name
+type clusterVec interface {
+	prometheus.Collector
+	Delete(labels prometheus.Labels) bool
+}
+
+var clusterVecs = []clusterVec{clusterScrapeDuration, requestDuration, requestPhaseDuration, responses, requestErrors, retries, logins, loginFailures}
+
+// RegisterExporterMetrics registers the metrics describing the exporter own behaviour
+func RegisterExporterMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range clusterVecs {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteClusterSeries deletes the series of a cluster no longer provided, the label values of every series
+// are read back from the vectors since they are not known in advance (eg: response codes)
+func deleteClusterSeries(name string) {
+	for _, vec := range clusterVecs {
+		for _, labels := range clusterSeries(vec, name) {
+			vec.Delete(labels)
+		}
+	}
+}
+
+func clusterSeries(vec prometheus.Collector, name string) []prometheus.Labels {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		vec.Collect(ch)
+		close(ch)
+	}()
+
+	series := make([]prometheus.Labels, 0)
+	for metric := range ch {
+		var written dto.Metric
+		if err := metric.Write(&written); err != nil {
+			continue
+		}
+
+		labels := make(prometheus.Labels, len(written.Label))
+		for _, pair := range written.Label {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+
+		if labels[clusterNameLabel] == name {
+			series = append(series, labels)
+		}
+	}
+
+	return series
+}
+
+type clusterNameKey struct{}
+
+// withClusterName stores the name of the collected cluster in the context to label the request metrics
+func withClusterName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, clusterNameKey{}, name)
+}
+
+func clusterNameFrom(ctx context.Context) string {
+	name, _ := ctx.Value(clusterNameKey{}).(string)
+	return name
+}
+
+// parameterizedEndpoints are reported without their parameters to keep the endpoint label bounded
+var parameterizedEndpoints = []string{"/v1/resourceGroupState/", "/v1/jmx/mbean/"}
+
+func endpointOf(path string) string {
+	for _, endpoint := range parameterizedEndpoints {
+		if strings.HasPrefix(path, endpoint) {
+			return strings.TrimSuffix(endpoint, "/")
+		}
+	}
+	return path
+}
+
+// requestObserver records the metrics of a single request
+type requestObserver struct {
+	cluster  string
+	endpoint string
+	start    time.Time
+
+	mutex        sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+}
+
+func newRequestObserver(req *http.Request) *requestObserver {
+	return &requestObserver{
+		cluster:  clusterNameFrom(req.Context()),
+		endpoint: endpointOf(req.URL.Path),
+		start:    time.Now(),
+	}
+}
+
+// trace returns the request with a client trace recording the connection phases
+func (o *requestObserver) trace(req *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			o.mark(&o.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			o.observePhase("dns", &o.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			o.mark(&o.connectStart)
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			if err == nil {
+				o.observePhase("connect", &o.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			o.mark(&o.tlsStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				o.observePhase("tls", &o.tlsStart)
+			}
+		},
+		GotFirstResponseByte: func() {
+			requestPhaseDuration.WithLabelValues(o.cluster, o.endpoint, "first_byte").Observe(time.Since(o.start).Seconds())
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+func (o *requestObserver) mark(phaseStart *time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if phaseStart.IsZero() {
+		*phaseStart = time.Now()
+	}
+}
+
+func (o *requestObserver) observePhase(phase string, phaseStart *time.Time) {
+	o.mutex.Lock()
+	start := *phaseStart
+	o.mutex.Unlock()
+
+	if !start.IsZero() {
+		requestPhaseDuration.WithLabelValues(o.cluster, o.endpoint, phase).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (o *requestObserver) observeResponse(resp *http.Response) {
+	requestDuration.WithLabelValues(o.cluster, o.endpoint).Observe(time.Since(o.start).Seconds())
+	responses.WithLabelValues(o.cluster, o.endpoint, strconv.Itoa(resp.StatusCode)).Inc()
+}
+
+func (o *requestObserver) observeError(reason string) {
+	requestErrors.WithLabelValues(o.cluster, o.endpoint, reason).Inc()
+}
+
+// observeRequestError records a request failed before or after the round trip, eg: undecodable response bodies
+func observeRequestError(req *http.Request, reason string) {
+	requestErrors.WithLabelValues(clusterNameFrom(req.Context()), endpointOf(req.URL.Path), reason).Inc()
+}
+
+// errorReason classifies the error of a failed round trip on the error types only. The tls alerts received
+// from the coordinator (eg: a rejected client certificate) are reported by crypto/tls as net.OpError with
+// the "remote error" op, the certificate verification failures wrap the x509 errors
+func errorReason(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var certificateInvalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var recordHeaderErr tls.RecordHeaderError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return reasonDns
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return reasonTimeout
+	case errors.As(err, &unknownAuthorityErr), errors.As(err, &certificateInvalidErr),
+		errors.As(err, &hostnameErr), errors.As(err, &recordHeaderErr), errors.As(err, &opErr) && opErr.Op == "remote error":
+		return reasonTls
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return reasonConnect
+	}
+
+	return reasonOther
+}
+
+func statusReason(statusCode int) string {
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		return reasonAuth
+	}
+	return reasonHttpStatus
+}
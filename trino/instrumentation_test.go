@@ -0,0 +1,99 @@
+package trino
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRequestInstrumentation(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/ui/api/stats": statsResponse,
+		"/v1/node":      `{"invalid"`,
+	})
+	closed := httptest.NewServer(nil)
+	closed.Close()
+
+	// the metrics are global, the assertions are on the increments to run with -count
+	instrumentedResponses := testutil.ToFloat64(responses.WithLabelValues("instrumented", "/ui/api/stats", "200"))
+	instrumentedErrors := testutil.ToFloat64(requestErrors.WithLabelValues("instrumented", "/v1/node", reasonDecode))
+	unreachableErrors := testutil.ToFloat64(requestErrors.WithLabelValues("unreachable", "/ui/login", reasonConnect))
+	instrumentedRequests := sampleCount(t, requestDuration.WithLabelValues("instrumented", "/ui/api/stats"))
+	unreachableScrapes := sampleCount(t, clusterScrapeDuration.WithLabelValues("unreachable"))
+
+	exporter := NewExporter(staticClusterProvider{
+		"instrumented": {Host: server.URL},
+		"unreachable":  {Host: closed.URL},
+	}, 2, NewCollector(NewClient()), NewNodeCollector(NewClient()))
+	testutil.CollectAndCount(exporter)
+
+	require.Equal(t, instrumentedResponses+1, testutil.ToFloat64(responses.WithLabelValues("instrumented", "/ui/api/stats", "200")))
+	require.Equal(t, instrumentedErrors+1, testutil.ToFloat64(requestErrors.WithLabelValues("instrumented", "/v1/node", reasonDecode)))
+	require.Equal(t, unreachableErrors+1, testutil.ToFloat64(requestErrors.WithLabelValues("unreachable", "/ui/login", reasonConnect)))
+	require.Equal(t, instrumentedRequests+1, sampleCount(t, requestDuration.WithLabelValues("instrumented", "/ui/api/stats")))
+	require.Equal(t, unreachableScrapes+1, sampleCount(t, clusterScrapeDuration.WithLabelValues("unreachable")))
+}
+
+func TestRemovedClusterSeriesDeleted(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/ui/api/stats": statsResponse,
+	})
+
+	exporter := NewExporter(staticClusterProvider{
+		"kept":    {Host: server.URL},
+		"removed": {Host: server.URL},
+	}, 2, NewCollector(NewClient()))
+	testutil.CollectAndCount(exporter)
+
+	for _, vec := range []prometheus.Collector{clusterScrapeDuration, requestDuration, responses} {
+		require.NotEmpty(t, clusterSeries(vec, "removed"))
+	}
+
+	exporter.Reload(staticClusterProvider{"kept": {Host: server.URL}}, NewCollector(NewClient()))
+	testutil.CollectAndCount(exporter)
+
+	for _, vec := range clusterVecs {
+		require.Empty(t, clusterSeries(vec, "removed"))
+	}
+	require.NotEmpty(t, clusterSeries(responses, "kept"))
+}
+
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var metric dto.Metric
+	require.NoError(t, observer.(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestEndpointOf(t *testing.T) {
+	require.Equal(t, "/v1/query", endpointOf("/v1/query"))
+	require.Equal(t, "/v1/resourceGroupState", endpointOf("/v1/resourceGroupState/global/adhoc"))
+	require.Equal(t, "/v1/jmx/mbean", endpointOf("/v1/jmx/mbean/java.lang:type=Memory"))
+}
+
+func TestErrorReason(t *testing.T) {
+	tests := map[error]string{
+		&net.DNSError{Err: "no such host", Name: "trino.example.com"}: reasonDns,
+		context.DeadlineExceeded:                           reasonTimeout,
+		x509.UnknownAuthorityError{}:                       reasonTls,
+		x509.CertificateInvalidError{Reason: x509.Expired}: reasonTls,
+		x509.HostnameError{Host: "trino.example.com", Certificate: &x509.Certificate{}}: reasonTls,
+		tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}:   reasonTls,
+		&net.OpError{Op: "remote error", Err: errors.New("tls: bad certificate")}:       reasonTls,
+		&net.OpError{Op: "dial", Err: errors.New("connection refused")}:                 reasonConnect,
+		errors.New("unexpected tls: prefix in a message"):                               reasonOther,
+	}
+
+	for err, expected := range tests {
+		wrapped := &url.Error{Op: "Get", URL: "https://trino.example.com", Err: err}
+		require.Equal(t, expected, errorReason(wrapped), err.Error())
+	}
+}
@@ -0,0 +1,245 @@
+package trino
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"sort"
+)
+
+const (
+	JmxGauge   = "gauge"
+	JmxCounter = "counter"
+)
+
+var metricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// JmxMetric maps an attribute of an MBean exposed by /v1/jmx/mbean to a prometheus metric named
+// trino_cluster_jmx_<name>, key selects an item of composite attributes (eg: used of HeapMemoryUsage)
+type JmxMetric struct {
+	MBean     string `json:"mbean"`
+	Attribute string `json:"attribute"`
+	Key       string `json:"key,omitempty"`
+	Name      string `json:"name"`
+	Help      string `json:"help,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+var DefaultJmxMetrics = []JmxMetric{
+	{MBean: "trino.memory:name=ClusterMemoryManager", Attribute: "ClusterMemoryBytes", Name: "cluster_memory_bytes", Help: "Total memory of the cluster."},
+	{MBean: "trino.memory:name=ClusterMemoryManager", Attribute: "QueriesKilledDueToOutOfMemory", Name: "queries_killed_due_to_out_of_memory_total", Type: JmxCounter, Help: "Queries killed by the cluster memory manager."},
+	{MBean: "trino.execution:name=QueryManager", Attribute: "StartedQueries.TotalCount", Name: "started_queries_total", Type: JmxCounter, Help: "Started queries."},
+	{MBean: "trino.execution:name=QueryManager", Attribute: "CompletedQueries.TotalCount", Name: "completed_queries_total", Type: JmxCounter, Help: "Completed queries."},
+	{MBean: "trino.execution:name=QueryManager", Attribute: "FailedQueries.TotalCount", Name: "failed_queries_total", Type: JmxCounter, Help: "Failed queries."},
+	{MBean: "trino.execution:name=QueryManager", Attribute: "AbandonedQueries.TotalCount", Name: "abandoned_queries_total", Type: JmxCounter, Help: "Abandoned queries."},
+	{MBean: "trino.execution:name=QueryManager", Attribute: "CanceledQueries.TotalCount", Name: "canceled_queries_total", Type: JmxCounter, Help: "Canceled queries."},
+	{MBean: "trino.execution:name=QueryManager", Attribute: "UserErrorFailures.TotalCount", Name: "user_error_failures_total", Type: JmxCounter, Help: "Queries failed by user errors."},
+	{MBean: "trino.execution:name=QueryManager", Attribute: "InternalFailures.TotalCount", Name: "internal_failures_total", Type: JmxCounter, Help: "Queries failed by internal errors."},
+	{MBean: "trino.spiller:name=SpillerFactory", Attribute: "TotalSpilledBytes", Name: "spilled_bytes_total", Type: JmxCounter, Help: "Bytes spilled to disk."},
+	{MBean: "io.airlift.http.client:type=HttpClient,name=ForScheduler", Attribute: "ActiveConnectionsPerDestination.Max", Name: "scheduler_http_client_active_connections_max", Help: "Max active connections per destination of the scheduler http client."},
+	{MBean: "io.airlift.http.client:type=HttpClient,name=ForScheduler", Attribute: "CurrentQueuedRequestsPerDestination.Max", Name: "scheduler_http_client_queued_requests_max", Help: "Max queued requests per destination of the scheduler http client."},
+	{MBean: "java.lang:type=Memory", Attribute: "HeapMemoryUsage", Key: "used", Name: "heap_used_bytes", Help: "Used heap memory of the jvm."},
+	{MBean: "java.lang:type=Memory", Attribute: "HeapMemoryUsage", Key: "committed", Name: "heap_committed_bytes", Help: "Committed heap memory of the jvm."},
+	{MBean: "java.lang:type=Memory", Attribute: "HeapMemoryUsage", Key: "max", Name: "heap_max_bytes", Help: "Max heap memory of the jvm."},
+	{MBean: "java.lang:type=GarbageCollector,name=G1 Young Generation", Attribute: "CollectionCount", Name: "gc_young_collections_total", Type: JmxCounter, Help: "Young generation collections of the jvm."},
+	{MBean: "java.lang:type=GarbageCollector,name=G1 Young Generation", Attribute: "CollectionTime", Name: "gc_young_collection_time_milliseconds_total", Type: JmxCounter, Help: "Young generation collection time of the jvm."},
+	{MBean: "java.lang:type=GarbageCollector,name=G1 Old Generation", Attribute: "CollectionCount", Name: "gc_old_collections_total", Type: JmxCounter, Help: "Old generation collections of the jvm."},
+	{MBean: "java.lang:type=GarbageCollector,name=G1 Old Generation", Attribute: "CollectionTime", Name: "gc_old_collection_time_milliseconds_total", Type: JmxCounter, Help: "Old generation collection time of the jvm."},
+}
+
+// LoadJmxMetrics reads a json array of JmxMetric from file
+func LoadJmxMetrics(path string) ([]JmxMetric, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []JmxMetric
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, fmt.Errorf("invalid jmx metrics file %s: %w", path, err)
+	}
+
+	return metrics, nil
+}
+
+func (m JmxMetric) validate() error {
+	if m.MBean == "" || m.Attribute == "" {
+		return fmt.Errorf("jmx metric %s: mbean and attribute are required", m.Name)
+	}
+
+	if !metricNameRegex.MatchString(m.Name) {
+		return fmt.Errorf("jmx metric %s: invalid metric name", m.Name)
+	}
+
+	if m.Type != "" && m.Type != JmxGauge && m.Type != JmxCounter {
+		return fmt.Errorf("jmx metric %s: invalid type %s, expected %s or %s", m.Name, m.Type, JmxGauge, JmxCounter)
+	}
+
+	return nil
+}
+
+type jmxMapping struct {
+	metric    JmxMetric
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+// jmxMappings groups the metrics by MBean so that every MBean is read once per collection
+type jmxMappings map[string][]jmxMapping
+
+func newJmxMappings(subsystem string, metrics []JmxMetric, labels []string) (jmxMappings, error) {
+	mappings := make(jmxMappings)
+	names := make(map[string]bool)
+
+	for _, metric := range metrics {
+		if err := metric.validate(); err != nil {
+			return nil, err
+		}
+
+		if names[metric.Name] {
+			return nil, fmt.Errorf("jmx metric %s: duplicated metric name", metric.Name)
+		}
+		names[metric.Name] = true
+
+		help := metric.Help
+		if help == "" {
+			help = fmt.Sprintf("%s %s", metric.MBean, metric.Attribute)
+		}
+
+		valueType := prometheus.GaugeValue
+		if metric.Type == JmxCounter {
+			valueType = prometheus.CounterValue
+		}
+
+		mappings[metric.MBean] = append(mappings[metric.MBean], jmxMapping{
+			metric:    metric,
+			desc:      newClusterDesc(subsystem, metric.Name, help, labels),
+			valueType: valueType,
+		})
+	}
+
+	return mappings, nil
+}
+
+func (m jmxMappings) describe(ch chan<- *prometheus.Desc) {
+	for _, mbeanMappings := range m {
+		for _, mapping := range mbeanMappings {
+			ch <- mapping.desc
+		}
+	}
+}
+
+// collect reads the mapped MBeans from the node at cluster.Host, MBeans missing on the node are skipped
+func (m jmxMappings) collect(ctx context.Context, client *Client, cluster ClusterInfo, out chan<- prometheus.Metric, labelValues ...string) error {
+	mbeans := make([]string, 0, len(m))
+	for mbean := range m {
+		mbeans = append(mbeans, mbean)
+	}
+	sort.Strings(mbeans)
+
+	for _, mbean := range mbeans {
+		attributes, err := client.mbeanAttributes(ctx, cluster, mbean)
+		if isNotFound(err) {
+			logrus.Debugf("mbean %s not found on %s", mbean, cluster.Host)
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, mapping := range m[mbean] {
+			value, err := attributeValue(attributes, mapping.metric)
+			if err != nil {
+				logrus.Debugf("%s on %s: %s", mbean, cluster.Host, err)
+				continue
+			}
+
+			out <- prometheus.MustNewConstMetric(mapping.desc, mapping.valueType, value, labelValues...)
+		}
+	}
+
+	return nil
+}
+
+func attributeValue(attributes map[string]interface{}, metric JmxMetric) (float64, error) {
+	value, present := attributes[metric.Attribute]
+	if !present {
+		return 0, fmt.Errorf("attribute %s not found", metric.Attribute)
+	}
+
+	if metric.Key != "" {
+		composite, ok := value.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("attribute %s is not a composite value", metric.Attribute)
+		}
+
+		value, present = composite[metric.Key]
+		if !present {
+			return 0, fmt.Errorf("key %s not found in attribute %s", metric.Key, metric.Attribute)
+		}
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		return boolToFloat(v), nil
+	}
+
+	return 0, fmt.Errorf("attribute %s has non numeric value %v", metric.Attribute, value)
+}
+
+// JmxCollector exports the configured MBean attributes of the coordinator
+type JmxCollector struct {
+	client   *Client
+	mappings jmxMappings
+}
+
+func NewJmxCollector(client *Client, metrics []JmxMetric) (JmxCollector, error) {
+	mappings, err := newJmxMappings("jmx", metrics, []string{"cluster_name"})
+	if err != nil {
+		return JmxCollector{}, err
+	}
+
+	return JmxCollector{
+		client:   client,
+		mappings: mappings,
+	}, nil
+}
+
+func (c JmxCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.mappings.describe(ch)
+}
+
+func (c JmxCollector) CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error {
+	return c.mappings.collect(ctx, c.client, cluster, out, name)
+}
+
+// mbeanAttributes returns the attribute values of the MBean by attribute name
+func (c *Client) mbeanAttributes(ctx context.Context, cluster ClusterInfo, objectName string) (map[string]interface{}, error) {
+	var mbean mbeanInfo
+	if err := c.getJSON(ctx, cluster, "/v1/jmx/mbean/"+url.PathEscape(cluster.Flavor.mbean(objectName)), &mbean); err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]interface{}, len(mbean.Attributes))
+	for _, attribute := range mbean.Attributes {
+		attributes[attribute.Name] = attribute.Value
+	}
+
+	return attributes, nil
+}
+
+type mbeanInfo struct {
+	ObjectName string `json:"objectName"`
+	Attributes []struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	} `json:"attributes"`
+}
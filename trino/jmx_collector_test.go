@@ -0,0 +1,49 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestJmxCollector(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/v1/jmx/mbean/java.lang:type=Memory": `{"objectName": "java.lang:type=Memory", "attributes": [
+			{"name": "HeapMemoryUsage", "value": {"committed": 2048, "init": 1024, "max": 4096, "used": 1536}},
+			{"name": "Verbose", "value": false}]}`,
+		"/v1/jmx/mbean/trino.execution:name=QueryManager": `{"objectName": "trino.execution:name=QueryManager", "attributes": [
+			{"name": "FailedQueries.TotalCount", "value": 12}]}`,
+	})
+
+	collector, err := NewJmxCollector(NewClient(), []JmxMetric{
+		{MBean: "java.lang:type=Memory", Attribute: "HeapMemoryUsage", Key: "used", Name: "heap_used_bytes", Help: "Used heap."},
+		{MBean: "trino.execution:name=QueryManager", Attribute: "FailedQueries.TotalCount", Name: "failed_queries_total", Help: "Failed queries.", Type: JmxCounter},
+		{MBean: "trino.memory:name=ClusterMemoryManager", Attribute: "ClusterMemoryBytes", Name: "cluster_memory_bytes", Help: "Cluster memory."},
+	})
+	require.NoError(t, err)
+
+	expected := `
+# HELP trino_cluster_jmx_failed_queries_total Failed queries.
+# TYPE trino_cluster_jmx_failed_queries_total counter
+trino_cluster_jmx_failed_queries_total{cluster_name="test"} 12
+# HELP trino_cluster_jmx_heap_used_bytes Used heap.
+# TYPE trino_cluster_jmx_heap_used_bytes gauge
+trino_cluster_jmx_heap_used_bytes{cluster_name="test"} 1536
+`
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, collector)
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected)))
+}
+
+func TestJmxCollectorInvalidMetrics(t *testing.T) {
+	_, err := NewJmxCollector(NewClient(), []JmxMetric{
+		{MBean: "java.lang:type=Memory", Attribute: "HeapMemoryUsage", Name: "invalid-name"},
+	})
+	require.Error(t, err)
+
+	_, err = NewJmxCollector(NewClient(), []JmxMetric{
+		{MBean: "java.lang:type=Memory", Attribute: "HeapMemoryUsage", Name: "heap", Type: "histogram"},
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package trino
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidateLabels checks the labels of a cluster are valid prometheus label names, cluster_name is reserved
+func ValidateLabels(labels map[string]string) error {
+	for name := range labels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+
+		if name == clusterNameLabel {
+			return fmt.Errorf("label %s is reserved", clusterNameLabel)
+		}
+	}
+	return nil
+}
+
+// metricLabels are the labels of the metrics of the collectors, the cluster labels with the same names are
+// dropped from those metrics. The federated metrics are not listed, their labels are known only when collected.
+// The metadata labels of the discovery providers are listed as well, namespace and service also collide with
+// the target labels of the prometheus kubernetes service discovery
+var metricLabels = []string{
+	"cluster_id", "coordinator", "environment", "namespace", "node_id", "parent_resource_group", "pool", "provider",
+	"query_id", "resource_group", "service", "source", "starting", "state", "uri", "user", "version",
+}
+
+// LabelCollisions returns the sorted labels of a cluster colliding with the labels of the collector metrics
+func LabelCollisions(labels map[string]string) []string {
+	collisions := make([]string, 0)
+	for _, name := range metricLabels {
+		if _, present := labels[name]; present {
+			collisions = append(collisions, name)
+		}
+	}
+	return collisions
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// SanitizeLabelName turns a tag or label key of a discovery provider into a valid label name with the
+// given prefix, eg: the kubernetes label app.kubernetes.io/team becomes label_app_kubernetes_io_team
+func SanitizeLabelName(prefix string, key string) string {
+	return prefix + invalidLabelChars.ReplaceAllString(key, "_")
+}
+
+// SanitizedLabelCollisions returns the keys of a discovery provider sanitized to the same label name by label
+// name, eg: cost-center and cost.center both become tag_cost_center
+func SanitizedLabelCollisions(prefix string, keys []string) map[string][]string {
+	byName := make(map[string][]string, len(keys))
+	for _, key := range keys {
+		name := SanitizeLabelName(prefix, key)
+		byName[name] = append(byName[name], key)
+	}
+
+	collisions := make(map[string][]string)
+	for name, keys := range byName {
+		if len(keys) > 1 {
+			collisions[name] = keys
+		}
+	}
+	return collisions
+}
+
+// labelPairs returns the labels sorted by name
+func labelPairs(labels map[string]string) []*dto.LabelPair {
+	pairs := make([]*dto.LabelPair, 0, len(labels))
+	for name, value := range labels {
+		name, value := name, value
+		pairs = append(pairs, &dto.LabelPair{Name: &name, Value: &value})
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+
+	return pairs
+}
+
+// labelledMetric is a metric exported with the labels of its cluster, the labels the metric already has win.
+// The desc is unchanged, the labels of the clusters are not known when describing the metrics
+type labelledMetric struct {
+	prometheus.Metric
+	labels []*dto.LabelPair
+}
+
+func (m labelledMetric) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+
+	present := make(map[string]bool, len(out.Label))
+	for _, pair := range out.Label {
+		present[pair.GetName()] = true
+	}
+
+	for _, pair := range m.labels {
+		if !present[pair.GetName()] {
+			out.Label = append(out.Label, pair)
+		}
+	}
+
+	sort.Slice(out.Label, func(i, j int) bool { return out.Label[i].GetName() < out.Label[j].GetName() })
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package trino
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const waitingForMemory = "WAITING_FOR_MEMORY"
+
+// defaultMemoryPool is the pool name used by the coordinators exposing a single memory pool
+const defaultMemoryPool = "general"
+
+var memoryPoolLabels = []string{"cluster_name", "pool"}
+
+var (
+	memoryPoolMaxBytes = newClusterDesc(
+		"memory_pool", "max_bytes",
+		"Max bytes of the cluster memory pool.",
+		memoryPoolLabels,
+	)
+	memoryPoolReservedBytes = newClusterDesc(
+		"memory_pool", "reserved_bytes",
+		"Reserved bytes of the cluster memory pool.",
+		memoryPoolLabels,
+	)
+	memoryPoolReservedRevocableBytes = newClusterDesc(
+		"memory_pool", "reserved_revocable_bytes",
+		"Reserved revocable bytes of the cluster memory pool.",
+		memoryPoolLabels,
+	)
+	memoryPoolFreeBytes = newClusterDesc(
+		"memory_pool", "free_bytes",
+		"Free bytes of the cluster memory pool.",
+		memoryPoolLabels,
+	)
+	memoryPoolBlockedNodes = newClusterDesc(
+		"memory_pool", "blocked_nodes",
+		"Nodes blocked on the cluster memory pool.",
+		memoryPoolLabels,
+	)
+	memoryPoolAssignedQueries = newClusterDesc(
+		"memory_pool", "assigned_queries",
+		"Queries assigned to the cluster memory pool.",
+		memoryPoolLabels,
+	)
+	memoryBlockedQueries = newClusterDesc(
+		"", "memory_blocked_queries",
+		"Running queries of the trino cluster fully blocked waiting for memory.",
+		[]string{"cluster_name"},
+	)
+)
+
+// MemoryCollector exports the cluster memory pools (/v1/cluster/memory) and the queries blocked on memory (/v1/query)
+type MemoryCollector struct {
+	client *Client
+}
+
+func NewMemoryCollector(client *Client) MemoryCollector {
+	return MemoryCollector{
+		client: client,
+	}
+}
+
+func (c MemoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- memoryPoolMaxBytes
+	ch <- memoryPoolReservedBytes
+	ch <- memoryPoolReservedRevocableBytes
+	ch <- memoryPoolFreeBytes
+	ch <- memoryPoolBlockedNodes
+	ch <- memoryPoolAssignedQueries
+	ch <- memoryBlockedQueries
+}
+
+func (c MemoryCollector) CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error {
+	pools, err := c.client.memoryPools(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	for pool, info := range pools {
+		memory := info.MemoryPoolInfo
+		free := memory.MaxBytes - memory.ReservedBytes - memory.ReservedRevocableBytes
+
+		out <- prometheus.MustNewConstMetric(memoryPoolMaxBytes, prometheus.GaugeValue, memory.MaxBytes, name, pool)
+		out <- prometheus.MustNewConstMetric(memoryPoolReservedBytes, prometheus.GaugeValue, memory.ReservedBytes, name, pool)
+		out <- prometheus.MustNewConstMetric(memoryPoolReservedRevocableBytes, prometheus.GaugeValue, memory.ReservedRevocableBytes, name, pool)
+		out <- prometheus.MustNewConstMetric(memoryPoolFreeBytes, prometheus.GaugeValue, free, name, pool)
+		out <- prometheus.MustNewConstMetric(memoryPoolBlockedNodes, prometheus.GaugeValue, info.BlockedNodes, name, pool)
+		out <- prometheus.MustNewConstMetric(memoryPoolAssignedQueries, prometheus.GaugeValue, info.AssignedQueries, name, pool)
+	}
+
+	queryList, err := c.client.queries(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	blocked := 0
+	for _, query := range queryList {
+		if query.State == "RUNNING" && query.QueryStats.isBlockedBy(waitingForMemory) {
+			blocked++
+		}
+	}
+
+	out <- prometheus.MustNewConstMetric(memoryBlockedQueries, prometheus.GaugeValue, float64(blocked), name)
+
+	return nil
+}
+
+func (s queryStats) isBlockedBy(reason string) bool {
+	if !s.FullyBlocked {
+		return false
+	}
+
+	for _, blockedReason := range s.BlockedReasons {
+		if blockedReason == reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+// memoryPools returns the cluster memory pools by name
+func (c *Client) memoryPools(ctx context.Context, cluster ClusterInfo) (clusterMemoryPools, error) {
+	var pools clusterMemoryPools
+	if err := c.getJSON(ctx, cluster, "/v1/cluster/memory", &pools); err != nil {
+		return nil, err
+	}
+
+	return pools, nil
+}
+
+// clusterMemoryPools are the memory pools by name, older coordinators expose a map of pools while newer
+// ones expose only the general pool
+type clusterMemoryPools map[string]clusterMemoryPoolInfo
+
+func (p *clusterMemoryPools) UnmarshalJSON(data []byte) error {
+	var shape struct {
+		MemoryPoolInfo json.RawMessage `json:"memoryPoolInfo"`
+	}
+	if err := json.Unmarshal(data, &shape); err != nil {
+		return err
+	}
+
+	if shape.MemoryPoolInfo != nil {
+		var pool clusterMemoryPoolInfo
+		if err := json.Unmarshal(data, &pool); err != nil {
+			return err
+		}
+		*p = clusterMemoryPools{defaultMemoryPool: pool}
+		return nil
+	}
+
+	pools := make(map[string]clusterMemoryPoolInfo)
+	if err := json.Unmarshal(data, &pools); err != nil {
+		return err
+	}
+	*p = pools
+
+	return nil
+}
+
+type clusterMemoryPoolInfo struct {
+	MemoryPoolInfo  memoryPoolInfo `json:"memoryPoolInfo"`
+	BlockedNodes    float64        `json:"blockedNodes"`
+	AssignedQueries float64        `json:"assignedQueries"`
+}
@@ -0,0 +1,76 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const memoryQueryListResponse = `[
+	{"queryId": "q1", "state": "RUNNING", "queryStats": {"fullyBlocked": true, "blockedReasons": ["WAITING_FOR_MEMORY"]}},
+	{"queryId": "q2", "state": "RUNNING", "queryStats": {"fullyBlocked": false}}
+]`
+
+func TestMemoryCollectorSinglePool(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/v1/cluster/memory": `{"totalDistributedBytes": 4096, "blockedNodes": 1, "assignedQueries": 3,
+			"memoryPoolInfo": {"maxBytes": 4096, "reservedBytes": 1024, "reservedRevocableBytes": 512}}`,
+		"/v1/query": memoryQueryListResponse,
+	})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewMemoryCollector(NewClient()))
+
+	expected := `
+# HELP trino_cluster_memory_blocked_queries Running queries of the trino cluster fully blocked waiting for memory.
+# TYPE trino_cluster_memory_blocked_queries gauge
+trino_cluster_memory_blocked_queries{cluster_name="test"} 1
+# HELP trino_cluster_memory_pool_assigned_queries Queries assigned to the cluster memory pool.
+# TYPE trino_cluster_memory_pool_assigned_queries gauge
+trino_cluster_memory_pool_assigned_queries{cluster_name="test",pool="general"} 3
+# HELP trino_cluster_memory_pool_blocked_nodes Nodes blocked on the cluster memory pool.
+# TYPE trino_cluster_memory_pool_blocked_nodes gauge
+trino_cluster_memory_pool_blocked_nodes{cluster_name="test",pool="general"} 1
+# HELP trino_cluster_memory_pool_free_bytes Free bytes of the cluster memory pool.
+# TYPE trino_cluster_memory_pool_free_bytes gauge
+trino_cluster_memory_pool_free_bytes{cluster_name="test",pool="general"} 2560
+# HELP trino_cluster_memory_pool_max_bytes Max bytes of the cluster memory pool.
+# TYPE trino_cluster_memory_pool_max_bytes gauge
+trino_cluster_memory_pool_max_bytes{cluster_name="test",pool="general"} 4096
+# HELP trino_cluster_memory_pool_reserved_bytes Reserved bytes of the cluster memory pool.
+# TYPE trino_cluster_memory_pool_reserved_bytes gauge
+trino_cluster_memory_pool_reserved_bytes{cluster_name="test",pool="general"} 1024
+# HELP trino_cluster_memory_pool_reserved_revocable_bytes Reserved revocable bytes of the cluster memory pool.
+# TYPE trino_cluster_memory_pool_reserved_revocable_bytes gauge
+trino_cluster_memory_pool_reserved_revocable_bytes{cluster_name="test",pool="general"} 512
+`
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected)))
+}
+
+func TestMemoryCollectorPoolMap(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/v1/cluster/memory": `{
+			"general": {"blockedNodes": 2, "assignedQueries": 5,
+				"memoryPoolInfo": {"maxBytes": 8192, "reservedBytes": 8192, "reservedRevocableBytes": 0}},
+			"reserved": {"blockedNodes": 0, "assignedQueries": 1,
+				"memoryPoolInfo": {"maxBytes": 2048, "reservedBytes": 1024, "reservedRevocableBytes": 0}}}`,
+		"/v1/query": memoryQueryListResponse,
+	})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewMemoryCollector(NewClient()))
+
+	expected := `
+# HELP trino_cluster_memory_pool_blocked_nodes Nodes blocked on the cluster memory pool.
+# TYPE trino_cluster_memory_pool_blocked_nodes gauge
+trino_cluster_memory_pool_blocked_nodes{cluster_name="test",pool="general"} 2
+trino_cluster_memory_pool_blocked_nodes{cluster_name="test",pool="reserved"} 0
+# HELP trino_cluster_memory_pool_free_bytes Free bytes of the cluster memory pool.
+# TYPE trino_cluster_memory_pool_free_bytes gauge
+trino_cluster_memory_pool_free_bytes{cluster_name="test",pool="general"} 0
+trino_cluster_memory_pool_free_bytes{cluster_name="test",pool="reserved"} 1024
+`
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected),
+		"trino_cluster_memory_pool_blocked_nodes", "trino_cluster_memory_pool_free_bytes"))
+}
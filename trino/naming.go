@@ -0,0 +1,37 @@
+package trino
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// NameTemplate renders the cluster_name of the clusters found by a discovery provider from the metadata of
+// the cluster, missing map keys (eg: tags not set on the cluster) render as empty strings
+type NameTemplate struct {
+	template *template.Template
+}
+
+func NewNameTemplate(text string) (NameTemplate, error) {
+	parsed, err := template.New("cluster_name").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return NameTemplate{}, fmt.Errorf("invalid name template: %w", err)
+	}
+	return NameTemplate{template: parsed}, nil
+}
+
+// Name renders the cluster name, rendering an empty name is an error
+func (t NameTemplate) Name(data interface{}) (string, error) {
+	var name bytes.Buffer
+	if err := t.template.Execute(&name, data); err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(name.String()) == "" {
+		return "", errors.New("name template rendered an empty name")
+	}
+
+	return name.String(), nil
+}
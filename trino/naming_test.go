@@ -0,0 +1,29 @@
+package trino
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNameTemplate(t *testing.T) {
+	data := struct {
+		Name string
+		Tags map[string]string
+	}{Name: "analytics", Tags: map[string]string{"env": "prod"}}
+
+	template, err := NewNameTemplate(`{{.Tags.env}}-{{.Name}}{{with .Tags.team}}-{{.}}{{end}}`)
+	require.NoError(t, err)
+
+	name, err := template.Name(data)
+	require.NoError(t, err)
+	require.Equal(t, "prod-analytics", name)
+
+	template, err = NewNameTemplate(`{{.Tags.team}}`)
+	require.NoError(t, err)
+
+	_, err = template.Name(data)
+	require.EqualError(t, err, "name template rendered an empty name")
+
+	_, err = NewNameTemplate(`{{.Name`)
+	require.Error(t, err)
+}
@@ -0,0 +1,210 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	nodeInfo = newClusterDesc(
+		"", "node_info",
+		"Nodes known by the coordinator of the trino cluster.",
+		[]string{"cluster_name", "node_id", "uri", "version", "state"},
+	)
+	nodeRecentFailureRatio = newClusterDesc(
+		"", "node_recent_failure_ratio",
+		"Recent failure ratio of the coordinator requests to the node.",
+		[]string{"cluster_name", "node_id", "uri"},
+	)
+	nodeAgeSeconds = newClusterDesc(
+		"", "node_age_seconds",
+		"Time since the node has been discovered by the coordinator.",
+		[]string{"cluster_name", "node_id", "uri"},
+	)
+	nodeFailed = newClusterDesc(
+		"", "node_failed",
+		"Whether the node is considered failed by the coordinator.",
+		[]string{"cluster_name", "node_id", "uri"},
+	)
+	failedNodes = newClusterDesc(
+		"", "failed_nodes",
+		"Failed nodes of the trino cluster.",
+		[]string{"cluster_name"},
+	)
+)
+
+const failedNodeState = "failed"
+
+// NodeCollector exports the nodes seen by the coordinator failure detector (/v1/node and /v1/node/failed),
+// node id, version and state are read from the web ui worker list when available. The worker list has the
+// ip of the nodes without port, a node gets the attributes of a worker only when they are the only ones on
+// their host: nodes sharing a host (eg: several workers on the same machine) are exported without them.
+// The worker list is not read from the clusters without web ui login (prestodb) and, for uiRecheckInterval,
+// from the clusters whose web ui has been found missing
+type NodeCollector struct {
+	client *Client
+	// noUI holds the time the web ui was found missing by cluster host
+	noUI *sync.Map
+}
+
+func NewNodeCollector(client *Client) NodeCollector {
+	return NodeCollector{
+		client: client,
+		noUI:   &sync.Map{},
+	}
+}
+
+func (c NodeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nodeInfo
+	ch <- nodeRecentFailureRatio
+	ch <- nodeAgeSeconds
+	ch <- nodeFailed
+	ch <- failedNodes
+}
+
+func (c NodeCollector) CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error {
+	nodes, err := c.client.nodes(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	failed, err := c.client.failedNodes(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	workers := c.workers(ctx, name, cluster)
+	workersByNode := matchWorkers(nodes, workers)
+
+	failedUris := make(map[string]bool, len(failed))
+	for _, node := range failed {
+		failedUris[node.Uri] = true
+	}
+
+	for _, node := range nodes {
+		worker := workersByNode[node.Uri]
+
+		state := worker.State
+		if failedUris[node.Uri] {
+			state = failedNodeState
+		}
+
+		out <- prometheus.MustNewConstMetric(nodeInfo, prometheus.GaugeValue, 1, name, worker.NodeId, node.Uri, worker.NodeVersion, state)
+		out <- prometheus.MustNewConstMetric(nodeRecentFailureRatio, prometheus.GaugeValue, node.RecentFailureRatio, name, worker.NodeId, node.Uri)
+		out <- prometheus.MustNewConstMetric(nodeAgeSeconds, prometheus.GaugeValue, float64(node.Age), name, worker.NodeId, node.Uri)
+		out <- prometheus.MustNewConstMetric(nodeFailed, prometheus.GaugeValue, boolToFloat(failedUris[node.Uri]), name, worker.NodeId, node.Uri)
+	}
+
+	out <- prometheus.MustNewConstMetric(failedNodes, prometheus.GaugeValue, float64(len(failed)), name)
+
+	return nil
+}
+
+// workers reads the worker list of the web ui, a missing web ui is remembered by host so that the login is
+// not attempted again on every scrape
+func (c NodeCollector) workers(ctx context.Context, name string, cluster ClusterInfo) []workerInfo {
+	if !cluster.Flavor.hasUILogin() || uiMissing(c.noUI, cluster) {
+		return nil
+	}
+
+	workers, err := c.client.workers(ctx, cluster)
+	if err == nil {
+		return workers
+	}
+
+	// the failed request is counted by the request metrics (trino_exporter_request_errors_total)
+	if isNotFound(err) {
+		logrus.Infof("web ui of %s not found, exporting the nodes without the worker list", cluster.Host)
+		c.noUI.Store(cluster.Host, time.Now())
+	} else {
+		logrus.Warnf("unable to read worker list of cluster %s: %s", name, err)
+	}
+
+	return nil
+}
+
+// matchWorkers returns the worker of the web ui list of every node uri, the worker list has no port so
+// that the nodes and the workers are matched by host only when the host is not shared
+func matchWorkers(nodes []nodeStats, workers []workerInfo) map[string]workerInfo {
+	nodesByHost := make(map[string][]string, len(nodes))
+	for _, node := range nodes {
+		host := hostOf(node.Uri)
+		nodesByHost[host] = append(nodesByHost[host], node.Uri)
+	}
+
+	workersByHost := make(map[string][]workerInfo, len(workers))
+	for _, worker := range workers {
+		workersByHost[worker.NodeIp] = append(workersByHost[worker.NodeIp], worker)
+	}
+
+	matched := make(map[string]workerInfo, len(nodes))
+	for host, uris := range nodesByHost {
+		hostWorkers := workersByHost[host]
+		if len(uris) == 1 && len(hostWorkers) == 1 {
+			matched[uris[0]] = hostWorkers[0]
+		} else if len(hostWorkers) > 0 {
+			logrus.Debugf("host %s shared by %d nodes and %d workers, node attributes not exported", host, len(uris), len(hostWorkers))
+		}
+	}
+
+	return matched
+}
+
+func hostOf(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func (c *Client) nodes(ctx context.Context, cluster ClusterInfo) ([]nodeStats, error) {
+	var nodes []nodeStats
+	if err := c.getJSON(ctx, cluster, "/v1/node", &nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+func (c *Client) failedNodes(ctx context.Context, cluster ClusterInfo) ([]nodeStats, error) {
+	var nodes []nodeStats
+	if err := c.getJSON(ctx, cluster, "/v1/node/failed", &nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+func (c *Client) workers(ctx context.Context, cluster ClusterInfo) ([]workerInfo, error) {
+	var workers []workerInfo
+	if err := c.getUIJSON(ctx, cluster, "/ui/api/worker", &workers); err != nil {
+		return nil, err
+	}
+
+	return workers, nil
+}
+
+type nodeStats struct {
+	Uri                string   `json:"uri"`
+	RecentFailureRatio float64  `json:"recentFailureRatio"`
+	Age                duration `json:"age"`
+}
+
+type workerInfo struct {
+	NodeId      string `json:"nodeId"`
+	NodeIp      string `json:"nodeIp"`
+	NodeVersion string `json:"nodeVersion"`
+	State       string `json:"state"`
+}
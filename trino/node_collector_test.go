@@ -0,0 +1,128 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const nodeListResponse = `[
+	{"uri": "http://10.0.0.1:8080", "recentFailureRatio": 0, "age": "1.00h"},
+	{"uri": "http://10.0.0.2:8080", "recentFailureRatio": 0.75, "age": "30.00m"},
+	{"uri": "http://10.0.0.3:8080", "recentFailureRatio": 0, "age": "10.00s"},
+	{"uri": "http://10.0.0.3:8081", "recentFailureRatio": 0, "age": "10.00s"}
+]`
+
+const workerListResponse = `[
+	{"nodeId": "worker-1", "nodeIp": "10.0.0.1", "nodeVersion": "360", "state": "active"},
+	{"nodeId": "worker-2", "nodeIp": "10.0.0.2", "nodeVersion": "360", "state": "inactive"},
+	{"nodeId": "worker-3", "nodeIp": "10.0.0.3", "nodeVersion": "360", "state": "active"},
+	{"nodeId": "worker-4", "nodeIp": "10.0.0.3", "nodeVersion": "359", "state": "active"}
+]`
+
+func TestNodeCollector(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/v1/node":        nodeListResponse,
+		"/v1/node/failed": `[{"uri": "http://10.0.0.2:8080", "recentFailureRatio": 0.75, "age": "30.00m"}]`,
+		"/ui/api/worker":  workerListResponse,
+	})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewNodeCollector(NewClient()))
+
+	expected := `
+# HELP trino_cluster_failed_nodes Failed nodes of the trino cluster.
+# TYPE trino_cluster_failed_nodes gauge
+trino_cluster_failed_nodes{cluster_name="test"} 1
+# HELP trino_cluster_node_failed Whether the node is considered failed by the coordinator.
+# TYPE trino_cluster_node_failed gauge
+trino_cluster_node_failed{cluster_name="test",node_id="",uri="http://10.0.0.3:8080"} 0
+trino_cluster_node_failed{cluster_name="test",node_id="",uri="http://10.0.0.3:8081"} 0
+trino_cluster_node_failed{cluster_name="test",node_id="worker-1",uri="http://10.0.0.1:8080"} 0
+trino_cluster_node_failed{cluster_name="test",node_id="worker-2",uri="http://10.0.0.2:8080"} 1
+# HELP trino_cluster_node_info Nodes known by the coordinator of the trino cluster.
+# TYPE trino_cluster_node_info gauge
+trino_cluster_node_info{cluster_name="test",node_id="",state="",uri="http://10.0.0.3:8080",version=""} 1
+trino_cluster_node_info{cluster_name="test",node_id="",state="",uri="http://10.0.0.3:8081",version=""} 1
+trino_cluster_node_info{cluster_name="test",node_id="worker-1",state="active",uri="http://10.0.0.1:8080",version="360"} 1
+trino_cluster_node_info{cluster_name="test",node_id="worker-2",state="failed",uri="http://10.0.0.2:8080",version="360"} 1
+# HELP trino_cluster_node_recent_failure_ratio Recent failure ratio of the coordinator requests to the node.
+# TYPE trino_cluster_node_recent_failure_ratio gauge
+trino_cluster_node_recent_failure_ratio{cluster_name="test",node_id="",uri="http://10.0.0.3:8080"} 0
+trino_cluster_node_recent_failure_ratio{cluster_name="test",node_id="",uri="http://10.0.0.3:8081"} 0
+trino_cluster_node_recent_failure_ratio{cluster_name="test",node_id="worker-1",uri="http://10.0.0.1:8080"} 0
+trino_cluster_node_recent_failure_ratio{cluster_name="test",node_id="worker-2",uri="http://10.0.0.2:8080"} 0.75
+`
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected),
+		"trino_cluster_failed_nodes", "trino_cluster_node_failed", "trino_cluster_node_info", "trino_cluster_node_recent_failure_ratio"))
+	require.Equal(t, 4, testutil.CollectAndCount(exporter, "trino_cluster_node_age_seconds"))
+}
+
+func TestNodeCollectorWithoutWorkerList(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/v1/node":        nodeListResponse,
+		"/v1/node/failed": `[]`,
+	})
+
+	workerListErrors := testutil.ToFloat64(requestErrors.WithLabelValues("no-worker-list", "/ui/api/worker", reasonHttpStatus))
+
+	exporter := NewExporter(staticClusterProvider{"no-worker-list": {Host: server.URL}}, 1, NewNodeCollector(NewClient()))
+
+	require.Equal(t, 4, testutil.CollectAndCount(exporter, "trino_cluster_node_info"))
+	require.Equal(t, workerListErrors+1, testutil.ToFloat64(requestErrors.WithLabelValues("no-worker-list", "/ui/api/worker", reasonHttpStatus)))
+}
+
+func TestNodeCollectorRemembersMissingWebUI(t *testing.T) {
+	var workerRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/node":
+			_, _ = w.Write([]byte(nodeListResponse))
+		case "/v1/node/failed":
+			_, _ = w.Write([]byte(`[]`))
+		case "/ui/api/worker":
+			atomic.AddInt32(&workerRequests, 1)
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	failures := loginFailures.WithLabelValues("missing-web-ui")
+	failed := testutil.ToFloat64(failures)
+
+	exporter := NewExporter(staticClusterProvider{"missing-web-ui": {Host: server.URL}}, 1, NewNodeCollector(NewClient()))
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, 4, testutil.CollectAndCount(exporter, "trino_cluster_node_info"))
+	}
+
+	require.Equal(t, failed+1, testutil.ToFloat64(failures))
+	require.Zero(t, atomic.LoadInt32(&workerRequests))
+}
+
+func TestNodeCollectorSkipsWorkerListWithoutUILogin(t *testing.T) {
+	var uiRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/node":
+			_, _ = w.Write([]byte(nodeListResponse))
+		case "/v1/node/failed":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			atomic.AddInt32(&uiRequests, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	exporter := NewExporter(staticClusterProvider{"prestodb": {Host: server.URL, Flavor: FlavorPrestoDB}}, 1, NewNodeCollector(NewClient()))
+
+	require.Equal(t, 4, testutil.CollectAndCount(exporter, "trino_cluster_node_info"))
+	require.Zero(t, atomic.LoadInt32(&uiRequests))
+}
@@ -0,0 +1,185 @@
+package trino
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// openMetricsFamily is the metadata of the family being converted, written before its first sample
+type openMetricsFamily struct {
+	name       string
+	metricType string
+	help       *string
+	written    bool
+}
+
+// openMetricsToText converts an openmetrics exposition to the prometheus text format: the input ends at the
+// # EOF line, counters are renamed to their _total samples, the _created samples and the exemplars are dropped,
+// the timestamps in seconds become milliseconds and the types unknown to the text format become untyped
+func openMetricsToText(body []byte) ([]byte, error) {
+	var out bytes.Buffer
+	family := &openMetricsFamily{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "# EOF":
+			return out.Bytes(), nil
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "# HELP ") || strings.HasPrefix(line, "# TYPE "):
+			fields := strings.SplitN(line[len("# HELP "):], " ", 2)
+			if fields[0] != family.name {
+				family = &openMetricsFamily{name: fields[0]}
+			}
+
+			value := ""
+			if len(fields) == 2 {
+				value = fields[1]
+			}
+
+			if strings.HasPrefix(line, "# HELP ") {
+				help := unescapeOpenMetricsHelp(value)
+				family.help = &help
+			} else {
+				family.metricType = value
+			}
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			sample, err := openMetricsSample(line, family)
+			if err != nil {
+				return nil, err
+			}
+			if sample == "" {
+				continue
+			}
+
+			if !family.written {
+				writeOpenMetricsFamily(&out, family)
+				family.written = true
+			}
+
+			out.WriteString(sample)
+			out.WriteByte('\n')
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("openmetrics exposition without # EOF")
+}
+
+// writeOpenMetricsFamily writes the help and the type of the family with their text format naming
+func writeOpenMetricsFamily(out *bytes.Buffer, family *openMetricsFamily) {
+	name, metricType := family.name, family.metricType
+
+	switch metricType {
+	case "counter":
+		name += "_total"
+	case "info":
+		name, metricType = name+"_info", "gauge"
+	case "stateset":
+		metricType = "gauge"
+	case "gauge", "histogram", "summary":
+	case "unknown":
+		metricType = "untyped"
+	default:
+		// gaugehistogram samples are exported as untyped families named after the samples
+		return
+	}
+
+	if family.help != nil {
+		fmt.Fprintf(out, "# HELP %s %s\n", name, textHelpEscaper.Replace(*family.help))
+	}
+	if metricType != "" {
+		fmt.Fprintf(out, "# TYPE %s %s\n", name, metricType)
+	}
+}
+
+// textHelpEscaper escapes the help of the text format, where only the backslash and the line feed are escaped
+var textHelpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// unescapeOpenMetricsHelp unescapes the \\, \n and \" escapes of an openmetrics help, other backslashes are kept
+func unescapeOpenMetricsHelp(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var help strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			switch value[i+1] {
+			case '\\', '"':
+				help.WriteByte(value[i+1])
+				i++
+				continue
+			case 'n':
+				help.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		help.WriteByte(value[i])
+	}
+	return help.String()
+}
+
+// openMetricsSample converts a sample line of the family, the samples not exported are returned empty
+func openMetricsSample(line string, family *openMetricsFamily) (string, error) {
+	end := strings.IndexAny(line, "{ ")
+	if end < 0 {
+		return "", fmt.Errorf("invalid openmetrics sample %q", line)
+	}
+
+	if line[end] == '{' {
+		quoted := false
+		for end++; end < len(line) && (quoted || line[end] != '}'); end++ {
+			switch {
+			case line[end] == '\\':
+				end++
+			case line[end] == '"':
+				quoted = !quoted
+			}
+		}
+		if end >= len(line) {
+			return "", fmt.Errorf("invalid openmetrics sample %q", line)
+		}
+		end++
+	}
+
+	name := line[:strings.IndexAny(line, "{ ")]
+	if name == family.name+"_created" {
+		return "", nil
+	}
+
+	// the exemplar follows the value and the timestamp after a #
+	rest := line[end:]
+	if exemplar := strings.Index(rest, "#"); exemplar >= 0 {
+		rest = rest[:exemplar]
+	}
+
+	fields := strings.Fields(rest)
+	switch len(fields) {
+	case 1:
+		return line[:end] + " " + fields[0], nil
+	case 2:
+		seconds, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid openmetrics timestamp %q: %w", line, err)
+		}
+		return line[:end] + " " + fields[0] + " " + strconv.FormatInt(int64(math.Round(seconds*1000)), 10), nil
+	default:
+		return "", fmt.Errorf("invalid openmetrics sample %q", line)
+	}
+}
@@ -0,0 +1,216 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const exporterNamespace = "trino_exporter"
+
+var (
+	lastSuccessTimestamp = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "last_success_timestamp_seconds"),
+		"Timestamp of the last successful background collection of the cluster.",
+		[]string{"cluster_name"}, nil,
+	)
+	snapshotStale = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "snapshot_stale"),
+		"Whether the last successful background collection of the cluster is older than the staleness threshold.",
+		[]string{"cluster_name"}, nil,
+	)
+)
+
+// Poller collects the clusters in background instead of at scrape time: every cluster is polled by its own
+// loop every interval, or every PollInterval of the cluster when set, and the collection serves the last snapshot
+// of each cluster. Clusters are rediscovered every interval, loops of the clusters no longer returned by the
+// provider are stopped. Snapshots are stale after staleAfter, or after 3 poll intervals of the cluster when zero.
+type Poller struct {
+	exporter   Exporter
+	interval   time.Duration
+	staleAfter time.Duration
+	semaphore  chan struct{}
+
+	mutex   sync.RWMutex
+	targets map[string]*pollTarget
+}
+
+type pollTarget struct {
+	cancel      context.CancelFunc
+	cluster     ClusterInfo
+	metrics     []prometheus.Metric
+	lastSuccess time.Time
+}
+
+func NewPoller(exporter Exporter, interval time.Duration, staleAfter time.Duration) *Poller {
+	return &Poller{
+		exporter:   exporter,
+		interval:   interval,
+		staleAfter: staleAfter,
+		semaphore:  make(chan struct{}, exporter.parallelism),
+		targets:    make(map[string]*pollTarget),
+	}
+}
+
+// Run discovers and polls the clusters until ctx is done
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.discover(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Poller) discover(ctx context.Context) {
+	clusters, err := p.exporter.current().clusterProvider.Provide()
+	if err != nil {
+		logrus.Errorf("%s", err)
+		return
+	}
+
+	p.exporter.track(clusters)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for name, cluster := range clusters {
+		target, present := p.targets[name]
+		if !present {
+			loopCtx, cancel := context.WithCancel(ctx)
+			target = &pollTarget{cancel: cancel}
+			p.targets[name] = target
+
+			go p.poll(loopCtx, name, target)
+		}
+
+		target.cluster = cluster
+	}
+
+	for name, target := range p.targets {
+		if _, present := clusters[name]; !present {
+			target.cancel()
+			delete(p.targets, name)
+		}
+	}
+}
+
+func (p *Poller) poll(ctx context.Context, name string, target *pollTarget) {
+	interval := p.intervalOf(p.clusterOf(target))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.pollCluster(ctx, name, target)
+
+		if current := p.intervalOf(p.clusterOf(target)); current != interval {
+			interval = current
+			ticker.Reset(interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// intervalOf returns the poll interval of the cluster, the global one when the cluster does not override it
+func (p *Poller) intervalOf(cluster ClusterInfo) time.Duration {
+	if cluster.PollInterval > 0 {
+		return cluster.PollInterval
+	}
+	return p.interval
+}
+
+// staleAfterOf returns the age after which the snapshot of the cluster is stale
+func (p *Poller) staleAfterOf(cluster ClusterInfo) time.Duration {
+	if p.staleAfter > 0 {
+		return p.staleAfter
+	}
+	return 3 * p.intervalOf(cluster)
+}
+
+func (p *Poller) clusterOf(target *pollTarget) ClusterInfo {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return target.cluster
+}
+
+func (p *Poller) pollCluster(ctx context.Context, name string, target *pollTarget) {
+	select {
+	case p.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-p.semaphore }()
+
+	cluster := p.clusterOf(target)
+
+	metrics := make([]prometheus.Metric, 0)
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+
+	go func() {
+		for metric := range ch {
+			metrics = append(metrics, metric)
+		}
+		close(done)
+	}()
+
+	success := p.exporter.collectCluster(ctx, p.exporter.current().collectors, name, cluster, ch)
+	close(ch)
+	<-done
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	target.metrics = metrics
+	if success {
+		target.lastSuccess = time.Now()
+	}
+}
+
+func (p *Poller) Describe(ch chan<- *prometheus.Desc) {
+	descs := describe(p.exporter)
+	if len(descs) == 0 {
+		return
+	}
+
+	for _, desc := range descs {
+		ch <- desc
+	}
+
+	ch <- lastSuccessTimestamp
+	ch <- snapshotStale
+}
+
+func (p *Poller) Collect(out chan<- prometheus.Metric) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	for name, target := range p.targets {
+		for _, metric := range target.metrics {
+			out <- metric
+		}
+
+		lastSuccess := 0.0
+		if !target.lastSuccess.IsZero() {
+			lastSuccess = float64(target.lastSuccess.UnixNano()) / float64(time.Second)
+		}
+
+		stale := target.lastSuccess.IsZero() || time.Since(target.lastSuccess) > p.staleAfterOf(target.cluster)
+
+		out <- prometheus.MustNewConstMetric(lastSuccessTimestamp, prometheus.GaugeValue, lastSuccess, name)
+		out <- prometheus.MustNewConstMetric(snapshotStale, prometheus.GaugeValue, boolToFloat(stale), name)
+	}
+}
@@ -0,0 +1,91 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPollerServesSnapshots(t *testing.T) {
+	healthy := newTrinoServer(t, map[string]string{"/ui/api/stats": statsResponse})
+	broken := newTrinoServer(t, map[string]string{})
+
+	exporter := NewExporter(staticClusterProvider{
+		"healthy": {Host: healthy.URL},
+		"broken":  {Host: broken.URL},
+	}, 2, NewCollector(NewClient()))
+
+	poller := NewPoller(exporter, 50*time.Millisecond, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go poller.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		return testutil.CollectAndCount(poller, "trino_cluster_up") == 2
+	}, time.Second, 10*time.Millisecond)
+
+	expected := `
+# HELP trino_cluster_up trino-exporter health check.
+# TYPE trino_cluster_up gauge
+trino_cluster_up{cluster_name="broken"} 0
+trino_cluster_up{cluster_name="healthy"} 1
+# HELP trino_exporter_snapshot_stale Whether the last successful background collection of the cluster is older than the staleness threshold.
+# TYPE trino_exporter_snapshot_stale gauge
+trino_exporter_snapshot_stale{cluster_name="broken"} 1
+trino_exporter_snapshot_stale{cluster_name="healthy"} 0
+`
+
+	require.NoError(t, testutil.CollectAndCompare(poller, strings.NewReader(expected), "trino_cluster_up", "trino_exporter_snapshot_stale"))
+}
+
+func TestPollerClusterInterval(t *testing.T) {
+	var defaultPolls, slowPolls int32
+	countingServer := func(polls *int32) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/ui/login":
+				http.SetCookie(w, &http.Cookie{Name: "Trino-UI-Token", Value: "token"})
+				w.WriteHeader(http.StatusSeeOther)
+			case "/ui/api/stats":
+				atomic.AddInt32(polls, 1)
+				_, _ = w.Write([]byte(statsResponse))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	exporter := NewExporter(staticClusterProvider{
+		"default": {Host: countingServer(&defaultPolls).URL},
+		"slow":    {Host: countingServer(&slowPolls).URL, PollInterval: time.Hour},
+	}, 2, NewCollector(NewClient()))
+
+	poller := NewPoller(exporter, 20*time.Millisecond, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go poller.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&defaultPolls) >= 3
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&slowPolls))
+
+	expected := `
+# HELP trino_exporter_snapshot_stale Whether the last successful background collection of the cluster is older than the staleness threshold.
+# TYPE trino_exporter_snapshot_stale gauge
+trino_exporter_snapshot_stale{cluster_name="default"} 0
+trino_exporter_snapshot_stale{cluster_name="slow"} 0
+`
+
+	require.NoError(t, testutil.CollectAndCompare(poller, strings.NewReader(expected), "trino_exporter_snapshot_stale"))
+}
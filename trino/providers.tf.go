@@ -1,9 +1,26 @@
 package trino
 
-import "fmt"
-
+import (
+	"fmt"
+	"golang.org/x/oauth2"
+	"time"
+)
+
+// ClusterInfo is a coordinator to monitor, a non nil TokenSource authenticates the requests with a bearer
+// token instead of the credentials password. Labels are attached to every metric of the cluster and a
+// positive Timeout bounds the duration of its collection, a positive PollInterval overrides the interval of its
+// background polling
 type ClusterInfo struct {
-	Host string
+	Host         string
+	Credentials  Credentials
+	TokenSource  oauth2.TokenSource
+	TLS          TLSSettings
+	Flavor       Flavor
+	Labels       map[string]string
+	Timeout      time.Duration
+	PollInterval time.Duration
+	// unavailable is the reason the cluster can not be collected, eg: its password file is unreadable
+	unavailable error
 }
 
 type ClusterProvider interface {
@@ -0,0 +1,244 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"sort"
+	"sync"
+)
+
+const otherLabelValue = "other"
+
+var (
+	queries = newClusterDesc(
+		"", "queries",
+		"Queries of the trino cluster by state.",
+		[]string{"cluster_name", "state"},
+	)
+	queriesByUser = newClusterDesc(
+		"", "queries_by_user",
+		"Queries of the trino cluster by user and state.",
+		[]string{"cluster_name", "user", "state"},
+	)
+	queriesBySource = newClusterDesc(
+		"", "queries_by_source",
+		"Queries of the trino cluster by source and state.",
+		[]string{"cluster_name", "source", "state"},
+	)
+	queriesByResourceGroup = newClusterDesc(
+		"", "queries_by_resource_group",
+		"Queries of the trino cluster by resource group and state.",
+		[]string{"cluster_name", "resource_group", "state"},
+	)
+	runningQueryElapsedSeconds = newClusterDesc(
+		"", "running_query_elapsed_seconds",
+		"Elapsed time of the longest running queries of the trino cluster.",
+		[]string{"cluster_name", "query_id", "user"},
+	)
+	runningQueryMemoryBytes = newClusterDesc(
+		"", "running_query_memory_bytes",
+		"Total memory reservation of the longest running queries of the trino cluster.",
+		[]string{"cluster_name", "query_id", "user"},
+	)
+)
+
+// QueryCollector exports the queries known by the coordinator (/v1/query) aggregated by state, user, source
+// and resource group. To keep the label cardinality bounded only the maxLabelValues most frequent users, sources
+// and resource groups are exported, the remaining ones are aggregated under the "other" label value, and only the
+// maxRunningQueries longest running queries are exported individually, none when not positive. The queries of
+// a user, source or resource group actually named "other" are always aggregated under "other".
+type QueryCollector struct {
+	client            *Client
+	maxLabelValues    int
+	maxRunningQueries int
+}
+
+func NewQueryCollector(client *Client, maxLabelValues int, maxRunningQueries int) QueryCollector {
+	if maxRunningQueries < 0 {
+		maxRunningQueries = 0
+	}
+
+	return QueryCollector{
+		client:            client,
+		maxLabelValues:    maxLabelValues,
+		maxRunningQueries: maxRunningQueries,
+	}
+}
+
+func (c QueryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queries
+	ch <- queriesByUser
+	ch <- queriesBySource
+	ch <- queriesByResourceGroup
+	ch <- runningQueryElapsedSeconds
+	ch <- runningQueryMemoryBytes
+}
+
+func (c QueryCollector) CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error {
+	queryList, err := c.client.queries(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	byState := make(map[string]int)
+	for _, query := range queryList {
+		byState[query.State]++
+	}
+
+	for state, count := range byState {
+		out <- prometheus.MustNewConstMetric(queries, prometheus.GaugeValue, float64(count), name, state)
+	}
+
+	c.collectByLabel(name, queryList, queriesByUser, out, func(query queryInfo) string {
+		return query.Session.User
+	})
+
+	c.collectByLabel(name, queryList, queriesBySource, out, func(query queryInfo) string {
+		return query.Session.Source
+	})
+
+	c.collectByLabel(name, queryList, queriesByResourceGroup, out, func(query queryInfo) string {
+		return resourceGroupName(query.ResourceGroupId)
+	})
+
+	running := make([]queryInfo, 0)
+	for _, query := range queryList {
+		if query.State == "RUNNING" {
+			running = append(running, query)
+		}
+	}
+
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].QueryStats.ElapsedTime > running[j].QueryStats.ElapsedTime
+	})
+
+	if len(running) > c.maxRunningQueries {
+		running = running[:c.maxRunningQueries]
+	}
+
+	for _, query := range running {
+		out <- prometheus.MustNewConstMetric(runningQueryElapsedSeconds, prometheus.GaugeValue, float64(query.QueryStats.ElapsedTime), name, query.QueryId, query.Session.User)
+		out <- prometheus.MustNewConstMetric(runningQueryMemoryBytes, prometheus.GaugeValue, float64(query.QueryStats.TotalMemoryReservation), name, query.QueryId, query.Session.User)
+	}
+
+	return nil
+}
+
+// collectByLabel exports the query count by state and by the label value returned by labelOf. A label value
+// equal to otherLabelValue is never one of the top values, its queries are counted with the aggregated ones
+// instead of exporting the same series twice
+func (c QueryCollector) collectByLabel(name string, queryList []queryInfo, desc *prometheus.Desc, out chan<- prometheus.Metric, labelOf func(queryInfo) string) {
+	totals := make(map[string]int)
+	for _, query := range queryList {
+		totals[labelOf(query)]++
+	}
+	delete(totals, otherLabelValue)
+
+	allowed := topValues(totals, c.maxLabelValues)
+
+	type key struct {
+		value string
+		state string
+	}
+
+	counts := make(map[key]int)
+	for _, query := range queryList {
+		value := labelOf(query)
+		if !allowed[value] {
+			value = otherLabelValue
+		}
+		counts[key{value: value, state: query.State}]++
+	}
+
+	for k, count := range counts {
+		out <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(count), name, k.value, k.state)
+	}
+}
+
+// topValues returns the limit values with the highest count, a non positive limit doesn't limit the values
+func topValues(counts map[string]int, limit int) map[string]bool {
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] == counts[values[j]] {
+			return values[i] < values[j]
+		}
+		return counts[values[i]] > counts[values[j]]
+	})
+
+	if limit > 0 && len(values) > limit {
+		values = values[:limit]
+	}
+
+	top := make(map[string]bool, len(values))
+	for _, value := range values {
+		top[value] = true
+	}
+
+	return top
+}
+
+// queries returns the queries known by the coordinator, read once per cluster collection when the context
+// has a query cache: the list is shared by the collectors and must not be modified
+func (c *Client) queries(ctx context.Context, cluster ClusterInfo) ([]queryInfo, error) {
+	cache, present := ctx.Value(queryCacheKey{}).(*queryCache)
+	if !present {
+		return c.readQueries(ctx, cluster)
+	}
+
+	cache.once.Do(func() {
+		cache.queries, cache.err = c.readQueries(ctx, cluster)
+	})
+
+	return cache.queries, cache.err
+}
+
+func (c *Client) readQueries(ctx context.Context, cluster ClusterInfo) ([]queryInfo, error) {
+	var queryList []queryInfo
+	if err := c.getJSON(ctx, cluster, "/v1/query", &queryList); err != nil {
+		return nil, err
+	}
+
+	return queryList, nil
+}
+
+type queryCacheKey struct{}
+
+// queryCache is the query list of a cluster collection, /v1/query is the most expensive endpoint of the
+// coordinators and is read by several collectors
+type queryCache struct {
+	once    sync.Once
+	queries []queryInfo
+	err     error
+}
+
+// withQueryCache shares the query list among the requests made with the context
+func withQueryCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryCacheKey{}, &queryCache{})
+}
+
+type queryInfo struct {
+	QueryId         string   `json:"queryId"`
+	State           string   `json:"state"`
+	ResourceGroupId []string `json:"resourceGroupId"`
+	Session         struct {
+		User   string `json:"user"`
+		Source string `json:"source"`
+	} `json:"session"`
+	QueryStats queryStats `json:"queryStats"`
+}
+
+type queryStats struct {
+	ElapsedTime            duration `json:"elapsedTime"`
+	TotalMemoryReservation dataSize `json:"totalMemoryReservation"`
+	UserMemoryReservation  dataSize `json:"userMemoryReservation"`
+	RawInputDataSize       dataSize `json:"rawInputDataSize"`
+	RawInputPositions      float64  `json:"rawInputPositions"`
+	TotalCpuTime           duration `json:"totalCpuTime"`
+	RunningDrivers         float64  `json:"runningDrivers"`
+	FullyBlocked           bool     `json:"fullyBlocked"`
+	BlockedReasons         []string `json:"blockedReasons"`
+}
@@ -0,0 +1,118 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type staticClusterProvider map[string]ClusterInfo
+
+func (s staticClusterProvider) Provide() (map[string]ClusterInfo, error) {
+	return s, nil
+}
+
+const queryListResponse = `[
+  {"queryId": "q1", "state": "RUNNING", "resourceGroupId": ["global", "adhoc"], "session": {"user": "alice", "source": "cli"},
+   "queryStats": {"elapsedTime": "2.00m", "totalMemoryReservation": "1.50GB"}},
+  {"queryId": "q2", "state": "RUNNING", "resourceGroupId": ["global", "etl"], "session": {"user": "bob", "source": "airflow"},
+   "queryStats": {"elapsedTime": "10.00s", "totalMemoryReservation": "512MB"}},
+  {"queryId": "q3", "state": "QUEUED", "resourceGroupId": ["global", "etl"], "session": {"user": "bob", "source": "airflow"},
+   "queryStats": {"elapsedTime": "150.00ms", "totalMemoryReservation": "0B"}},
+  {"queryId": "q4", "state": "FINISHED", "resourceGroupId": ["global", "adhoc"], "session": {"user": "carol"},
+   "queryStats": {"elapsedTime": "1.00h", "totalMemoryReservation": "0B"}}
+]`
+
+func newTrinoServer(t *testing.T, responses map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ui/login" {
+			http.SetCookie(w, &http.Cookie{Name: "Trino-UI-Token", Value: "token"})
+			w.WriteHeader(http.StatusSeeOther)
+			return
+		}
+
+		body, present := responses[r.URL.Path]
+		if !present {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := w.Write([]byte(body))
+		require.NoError(t, err)
+	}))
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestQueryCollector(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{"/v1/query": queryListResponse})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewQueryCollector(NewClient(), 1, 1))
+
+	expected := `
+# HELP trino_cluster_queries_by_user Queries of the trino cluster by user and state.
+# TYPE trino_cluster_queries_by_user gauge
+trino_cluster_queries_by_user{cluster_name="test",state="FINISHED",user="other"} 1
+trino_cluster_queries_by_user{cluster_name="test",state="QUEUED",user="bob"} 1
+trino_cluster_queries_by_user{cluster_name="test",state="RUNNING",user="bob"} 1
+trino_cluster_queries_by_user{cluster_name="test",state="RUNNING",user="other"} 1
+# HELP trino_cluster_running_query_elapsed_seconds Elapsed time of the longest running queries of the trino cluster.
+# TYPE trino_cluster_running_query_elapsed_seconds gauge
+trino_cluster_running_query_elapsed_seconds{cluster_name="test",query_id="q1",user="alice"} 120
+# HELP trino_cluster_running_query_memory_bytes Total memory reservation of the longest running queries of the trino cluster.
+# TYPE trino_cluster_running_query_memory_bytes gauge
+trino_cluster_running_query_memory_bytes{cluster_name="test",query_id="q1",user="alice"} 1.610612736e+09
+`
+
+	err := testutil.CollectAndCompare(exporter, strings.NewReader(expected),
+		"trino_cluster_queries_by_user", "trino_cluster_running_query_elapsed_seconds", "trino_cluster_running_query_memory_bytes")
+	require.NoError(t, err)
+}
+
+func TestQueryCollectorNegativeMaxRunning(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{"/v1/query": queryListResponse})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewQueryCollector(NewClient(), 20, -1))
+
+	require.Equal(t, 0, testutil.CollectAndCount(exporter, "trino_cluster_running_query_elapsed_seconds"))
+	require.NotZero(t, testutil.CollectAndCount(exporter, "trino_cluster_queries"))
+}
+
+func TestQueryCollectorRealOtherValue(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{"/v1/query": `[
+  {"queryId": "q1", "state": "RUNNING", "session": {"user": "other"}},
+  {"queryId": "q2", "state": "RUNNING", "session": {"user": "other"}},
+  {"queryId": "q3", "state": "RUNNING", "session": {"user": "alice"}},
+  {"queryId": "q4", "state": "RUNNING", "session": {"user": "bob"}}
+]`})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewQueryCollector(NewClient(), 1, 0))
+
+	expected := `
+# HELP trino_cluster_queries_by_user Queries of the trino cluster by user and state.
+# TYPE trino_cluster_queries_by_user gauge
+trino_cluster_queries_by_user{cluster_name="test",state="RUNNING",user="alice"} 1
+trino_cluster_queries_by_user{cluster_name="test",state="RUNNING",user="other"} 3
+`
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected), "trino_cluster_queries_by_user"))
+}
+
+func TestParseWithUnit(t *testing.T) {
+	value, err := parseWithUnit("1.50s", durationUnits)
+	require.NoError(t, err)
+	require.Equal(t, 1.5, value)
+
+	value, err = parseWithUnit("2kB", dataSizeUnits)
+	require.NoError(t, err)
+	require.Equal(t, 2048.0, value)
+
+	_, err = parseWithUnit("12", durationUnits)
+	require.Error(t, err)
+
+	_, err = parseWithUnit("12parsec", durationUnits)
+	require.Error(t, err)
+}
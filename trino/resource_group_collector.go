@@ -0,0 +1,218 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var resourceGroupLabels = []string{"cluster_name", "resource_group", "parent_resource_group"}
+
+var (
+	resourceGroupRunningQueries = newClusterDesc(
+		"resource_group", "running_queries",
+		"Running queries of the resource group.",
+		resourceGroupLabels,
+	)
+	resourceGroupQueuedQueries = newClusterDesc(
+		"resource_group", "queued_queries",
+		"Queued queries of the resource group.",
+		resourceGroupLabels,
+	)
+	resourceGroupSoftConcurrencyLimit = newClusterDesc(
+		"resource_group", "soft_concurrency_limit",
+		"Soft concurrency limit of the resource group.",
+		resourceGroupLabels,
+	)
+	resourceGroupHardConcurrencyLimit = newClusterDesc(
+		"resource_group", "hard_concurrency_limit",
+		"Hard concurrency limit of the resource group.",
+		resourceGroupLabels,
+	)
+	resourceGroupMaxQueuedQueries = newClusterDesc(
+		"resource_group", "max_queued_queries",
+		"Max queued queries of the resource group.",
+		resourceGroupLabels,
+	)
+	resourceGroupMemoryUsageBytes = newClusterDesc(
+		"resource_group", "memory_usage_bytes",
+		"Memory usage of the resource group.",
+		resourceGroupLabels,
+	)
+	resourceGroupSoftMemoryLimitBytes = newClusterDesc(
+		"resource_group", "soft_memory_limit_bytes",
+		"Soft memory limit of the resource group.",
+		resourceGroupLabels,
+	)
+	resourceGroupCpuUsageSeconds = newClusterDesc(
+		"resource_group", "cpu_usage_seconds",
+		"Cpu usage of the resource group.",
+		resourceGroupLabels,
+	)
+)
+
+// ResourceGroupCollector exports the state of the resource groups (/v1/resourceGroupState) walking the group tree
+// from the root groups. Root groups are the configured ones plus the ones used by the queries known by the coordinator,
+// as the coordinator doesn't expose a list of the existing groups.
+type ResourceGroupCollector struct {
+	client     *Client
+	rootGroups []string
+}
+
+func NewResourceGroupCollector(client *Client, rootGroups []string) ResourceGroupCollector {
+	return ResourceGroupCollector{
+		client:     client,
+		rootGroups: rootGroups,
+	}
+}
+
+func (c ResourceGroupCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- resourceGroupRunningQueries
+	ch <- resourceGroupQueuedQueries
+	ch <- resourceGroupSoftConcurrencyLimit
+	ch <- resourceGroupHardConcurrencyLimit
+	ch <- resourceGroupMaxQueuedQueries
+	ch <- resourceGroupMemoryUsageBytes
+	ch <- resourceGroupSoftMemoryLimitBytes
+	ch <- resourceGroupCpuUsageSeconds
+}
+
+func (c ResourceGroupCollector) CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error {
+	roots, err := c.discoverRootGroups(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	for _, root := range roots {
+		if err := c.collectGroup(ctx, name, cluster, []string{root}, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c ResourceGroupCollector) discoverRootGroups(ctx context.Context, cluster ClusterInfo) ([]string, error) {
+	roots := make(map[string]bool)
+	for _, root := range c.rootGroups {
+		roots[root] = true
+	}
+
+	queryList, err := c.client.queries(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, query := range queryList {
+		if len(query.ResourceGroupId) > 0 {
+			roots[query.ResourceGroupId[0]] = true
+		}
+	}
+
+	result := make([]string, 0, len(roots))
+	for root := range roots {
+		result = append(result, root)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+func (c ResourceGroupCollector) collectGroup(ctx context.Context, name string, cluster ClusterInfo, id []string, out chan<- prometheus.Metric) error {
+	group, err := c.client.resourceGroup(ctx, cluster, id)
+	if isNotFound(err) {
+		// groups are created lazily by the coordinator, configured roots may not exist yet
+		logrus.Debugf("resource group %s not found in cluster %s", resourceGroupName(id), name)
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	labelValues := []string{name, resourceGroupName(id), resourceGroupName(id[:len(id)-1])}
+
+	out <- prometheus.MustNewConstMetric(resourceGroupRunningQueries, prometheus.GaugeValue, group.NumRunningQueries, labelValues...)
+	out <- prometheus.MustNewConstMetric(resourceGroupQueuedQueries, prometheus.GaugeValue, group.NumQueuedQueries, labelValues...)
+	out <- prometheus.MustNewConstMetric(resourceGroupSoftConcurrencyLimit, prometheus.GaugeValue, group.SoftConcurrencyLimit, labelValues...)
+	out <- prometheus.MustNewConstMetric(resourceGroupHardConcurrencyLimit, prometheus.GaugeValue, group.HardConcurrencyLimit, labelValues...)
+	out <- prometheus.MustNewConstMetric(resourceGroupMaxQueuedQueries, prometheus.GaugeValue, group.MaxQueuedQueries, labelValues...)
+	out <- prometheus.MustNewConstMetric(resourceGroupMemoryUsageBytes, prometheus.GaugeValue, float64(group.MemoryUsage), labelValues...)
+	out <- prometheus.MustNewConstMetric(resourceGroupSoftMemoryLimitBytes, prometheus.GaugeValue, float64(group.SoftMemoryLimit), labelValues...)
+	out <- prometheus.MustNewConstMetric(resourceGroupCpuUsageSeconds, prometheus.GaugeValue, float64(group.CpuUsage), labelValues...)
+
+	for _, subGroup := range group.SubGroups {
+		if !isSubGroup(id, subGroup.Id) {
+			logrus.Warnf("cluster %s: resource group %s skipped, not a sub group of %s", name, resourceGroupName(subGroup.Id), resourceGroupName(id))
+			continue
+		}
+
+		if err := c.collectGroup(ctx, name, cluster, subGroup.Id, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isSubGroup reports whether id is a direct sub group of parent, one segment longer and starting with the parent id,
+// so that malformed responses (eg: an empty id or the id of an ancestor) do not make the tree walk fail or loop
+func isSubGroup(parent []string, id []string) bool {
+	if len(id) != len(parent)+1 {
+		return false
+	}
+
+	for i, segment := range parent {
+		if id[i] != segment {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resourceGroupEscaper escapes the separator of the segments, and the escape character, inside a segment
+var resourceGroupEscaper = strings.NewReplacer(`\`, `\\`, ".", `\.`)
+
+// resourceGroupName joins the segments of a resource group id with ".", eg: global.adhoc. The dots and the
+// backslashes of a segment are escaped with a backslash so that global.adhoc and the segment "global.adhoc"
+// have different names
+func resourceGroupName(id []string) string {
+	segments := make([]string, len(id))
+	for i, segment := range id {
+		segments[i] = resourceGroupEscaper.Replace(segment)
+	}
+	return strings.Join(segments, ".")
+}
+
+func (c *Client) resourceGroup(ctx context.Context, cluster ClusterInfo, id []string) (resourceGroupInfo, error) {
+	segments := make([]string, len(id))
+	for i, segment := range id {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	var group resourceGroupInfo
+	if err := c.getJSON(ctx, cluster, "/v1/resourceGroupState/"+strings.Join(segments, "/"), &group); err != nil {
+		return resourceGroupInfo{}, err
+	}
+
+	return group, nil
+}
+
+type resourceGroupInfo struct {
+	Id                   []string `json:"id"`
+	SoftMemoryLimit      dataSize `json:"softMemoryLimit"`
+	SoftConcurrencyLimit float64  `json:"softConcurrencyLimit"`
+	HardConcurrencyLimit float64  `json:"hardConcurrencyLimit"`
+	MaxQueuedQueries     float64  `json:"maxQueuedQueries"`
+	MemoryUsage          dataSize `json:"memoryUsage"`
+	CpuUsage             duration `json:"cpuUsage"`
+	NumQueuedQueries     float64  `json:"numQueuedQueries"`
+	NumRunningQueries    float64  `json:"numRunningQueries"`
+	SubGroups            []struct {
+		Id []string `json:"id"`
+	} `json:"subGroups"`
+}
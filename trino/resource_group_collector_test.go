@@ -0,0 +1,88 @@
+package trino
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestResourceGroupCollector(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/v1/query": queryListResponse,
+		"/v1/resourceGroupState/global": `{"id": ["global"], "softMemoryLimit": "10GB", "softConcurrencyLimit": 100, "hardConcurrencyLimit": 100,
+			"maxQueuedQueries": 1000, "memoryUsage": "2GB", "numQueuedQueries": 1, "numRunningQueries": 2,
+			"subGroups": [{"id": ["global", "adhoc"]}, {"id": ["global", "etl"]}]}`,
+		"/v1/resourceGroupState/global/adhoc": `{"id": ["global", "adhoc"], "softConcurrencyLimit": 1, "hardConcurrencyLimit": 1,
+			"numQueuedQueries": 0, "numRunningQueries": 1, "subGroups": []}`,
+		"/v1/resourceGroupState/global/etl": `{"id": ["global", "etl"], "softConcurrencyLimit": 5, "hardConcurrencyLimit": 10,
+			"numQueuedQueries": 1, "numRunningQueries": 1}`,
+	})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewResourceGroupCollector(NewClient(), []string{"missing"}))
+
+	expected := `
+# HELP trino_cluster_resource_group_hard_concurrency_limit Hard concurrency limit of the resource group.
+# TYPE trino_cluster_resource_group_hard_concurrency_limit gauge
+trino_cluster_resource_group_hard_concurrency_limit{cluster_name="test",parent_resource_group="",resource_group="global"} 100
+trino_cluster_resource_group_hard_concurrency_limit{cluster_name="test",parent_resource_group="global",resource_group="global.adhoc"} 1
+trino_cluster_resource_group_hard_concurrency_limit{cluster_name="test",parent_resource_group="global",resource_group="global.etl"} 10
+# HELP trino_cluster_resource_group_memory_usage_bytes Memory usage of the resource group.
+# TYPE trino_cluster_resource_group_memory_usage_bytes gauge
+trino_cluster_resource_group_memory_usage_bytes{cluster_name="test",parent_resource_group="",resource_group="global"} 2.147483648e+09
+trino_cluster_resource_group_memory_usage_bytes{cluster_name="test",parent_resource_group="global",resource_group="global.adhoc"} 0
+trino_cluster_resource_group_memory_usage_bytes{cluster_name="test",parent_resource_group="global",resource_group="global.etl"} 0
+`
+
+	err := testutil.CollectAndCompare(exporter, strings.NewReader(expected),
+		"trino_cluster_resource_group_hard_concurrency_limit", "trino_cluster_resource_group_memory_usage_bytes")
+	require.NoError(t, err)
+}
+
+func TestResourceGroupCollectorDottedSegments(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/v1/query": `[]`,
+		"/v1/resourceGroupState/global": `{"id": ["global"], "hardConcurrencyLimit": 100,
+			"subGroups": [{"id": ["global", "adhoc"]}, {"id": ["global", "adhoc.bi"]}]}`,
+		"/v1/resourceGroupState/global/adhoc": `{"id": ["global", "adhoc"], "hardConcurrencyLimit": 1,
+			"subGroups": [{"id": ["global", "adhoc", "bi"]}]}`,
+		"/v1/resourceGroupState/global/adhoc/bi": `{"id": ["global", "adhoc", "bi"], "hardConcurrencyLimit": 2}`,
+		"/v1/resourceGroupState/global/adhoc.bi": `{"id": ["global", "adhoc.bi"], "hardConcurrencyLimit": 3}`,
+	})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewResourceGroupCollector(NewClient(), []string{"global"}))
+
+	expected := `
+# HELP trino_cluster_resource_group_hard_concurrency_limit Hard concurrency limit of the resource group.
+# TYPE trino_cluster_resource_group_hard_concurrency_limit gauge
+trino_cluster_resource_group_hard_concurrency_limit{cluster_name="test",parent_resource_group="",resource_group="global"} 100
+trino_cluster_resource_group_hard_concurrency_limit{cluster_name="test",parent_resource_group="global",resource_group="global.adhoc"} 1
+trino_cluster_resource_group_hard_concurrency_limit{cluster_name="test",parent_resource_group="global",resource_group="global.adhoc\\.bi"} 3
+trino_cluster_resource_group_hard_concurrency_limit{cluster_name="test",parent_resource_group="global.adhoc",resource_group="global.adhoc.bi"} 2
+`
+
+	err := testutil.CollectAndCompare(exporter, strings.NewReader(expected), "trino_cluster_resource_group_hard_concurrency_limit")
+	require.NoError(t, err)
+}
+
+func TestResourceGroupCollectorMalformedSubGroups(t *testing.T) {
+	server := newTrinoServer(t, map[string]string{
+		"/v1/query": `[]`,
+		"/v1/resourceGroupState/global": `{"id": ["global"], "hardConcurrencyLimit": 100,
+			"subGroups": [{"id": []}, {"id": ["global"]}, {"id": ["other", "adhoc"]}, {"id": ["global", "adhoc", "bi"]}, {"id": ["global", "etl"]}]}`,
+		"/v1/resourceGroupState/global/etl": `{"id": ["global", "etl"], "hardConcurrencyLimit": 10,
+			"subGroups": [{"id": ["global"]}]}`,
+	})
+
+	exporter := NewExporter(staticClusterProvider{"test": {Host: server.URL}}, 1, NewResourceGroupCollector(NewClient(), []string{"global"}))
+
+	expected := `
+# HELP trino_cluster_resource_group_hard_concurrency_limit Hard concurrency limit of the resource group.
+# TYPE trino_cluster_resource_group_hard_concurrency_limit gauge
+trino_cluster_resource_group_hard_concurrency_limit{cluster_name="test",parent_resource_group="",resource_group="global"} 100
+trino_cluster_resource_group_hard_concurrency_limit{cluster_name="test",parent_resource_group="global",resource_group="global.etl"} 10
+`
+
+	err := testutil.CollectAndCompare(exporter, strings.NewReader(expected), "trino_cluster_resource_group_hard_concurrency_limit")
+	require.NoError(t, err)
+}
@@ -0,0 +1,134 @@
+package trino
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// statsField is a field of the statistics response exported as metric
+type statsField struct {
+	name  string
+	desc  *prometheus.Desc
+	value func(response *Response) *float64
+}
+
+var statsFields = []statsField{
+	{"runningQueries", runningQueries, func(r *Response) *float64 { return &r.RunningQueries }},
+	{"blockedQueries", blockedQueries, func(r *Response) *float64 { return &r.BlockedQueries }},
+	{"queuedQueries", queuedQueries, func(r *Response) *float64 { return &r.QueuedQueries }},
+	{"activeWorkers", activeWorkers, func(r *Response) *float64 { return &r.ActiveWorkers }},
+	{"runningDrivers", runningDrivers, func(r *Response) *float64 { return &r.RunningDrivers }},
+	{"reservedMemory", reservedMemory, func(r *Response) *float64 { return &r.ReservedMemory }},
+	{"totalInputRows", totalInputRows, func(r *Response) *float64 { return &r.TotalInputRows }},
+	{"totalInputBytes", totalInputBytes, func(r *Response) *float64 { return &r.TotalInputBytes }},
+	{"totalCpuTimeSecs", totalCpuTimeSecs, func(r *Response) *float64 { return &r.TotalCpuTimeSecs }},
+}
+
+// coordinatorVersion is the release number of the coordinator, eg: 360 for trino 360, 350 for prestosql 350
+// and 245 for prestodb 0.245. Releases with a vendor suffix (eg: 360-e.1) are numbered as the base release
+type coordinatorVersion struct {
+	raw      string
+	known    bool
+	prestoDB bool
+	number   int
+}
+
+const unknownVersion = "unknown"
+
+var versionRegex = regexp.MustCompile(`^(0\.)?(\d+)`)
+
+func parseCoordinatorVersion(raw string) coordinatorVersion {
+	match := versionRegex.FindStringSubmatch(raw)
+	if match == nil {
+		return coordinatorVersion{raw: raw}
+	}
+
+	number, err := strconv.Atoi(match[2])
+	if err != nil {
+		return coordinatorVersion{raw: raw}
+	}
+
+	return coordinatorVersion{raw: raw, known: true, prestoDB: match[1] != "", number: number}
+}
+
+// statsAdapter reads the statistics of a range of coordinator releases: trino and prestosql serve them to
+// the web ui behind its login (/ui/api/stats), prestodb serves them on /v1/cluster without login
+type statsAdapter struct {
+	name    string
+	flavor  Flavor
+	matches func(version coordinatorVersion) bool
+	uiStats bool
+}
+
+// statsAdapters are tried in order on the detected version, the adapter of the flavor of the cluster is
+// used when the version is unknown
+var statsAdapters = []statsAdapter{
+	{
+		name:    "trino",
+		flavor:  FlavorTrino,
+		matches: func(version coordinatorVersion) bool { return !version.prestoDB && version.number >= 351 },
+		uiStats: true,
+	},
+	{
+		name:    "prestosql",
+		flavor:  FlavorPrestoSQL,
+		matches: func(version coordinatorVersion) bool { return !version.prestoDB && version.number < 351 },
+		uiStats: true,
+	},
+	{
+		name:    "prestodb",
+		flavor:  FlavorPrestoDB,
+		matches: func(version coordinatorVersion) bool { return version.prestoDB },
+		uiStats: false,
+	},
+}
+
+// statsAdapterFor returns the adapter matching the version, the one of the flavor when the version is unknown
+func statsAdapterFor(version coordinatorVersion, flavor Flavor) statsAdapter {
+	for _, adapter := range statsAdapters {
+		if (version.known && adapter.matches(version)) || (!version.known && adapter.flavor == flavor) {
+			return adapter
+		}
+	}
+	return statsAdapters[0]
+}
+
+var errUnexpectedStatsShape = errors.New("unexpected statistics response")
+
+// decodeStats reads the statistics returning the fields missing from the response, responses not being
+// an object, with non numeric fields or without any of the expected fields are rejected. A field missing
+// is reported instead of being exported as 0. The adapters share the field table, the releases they
+// cover use the same keys
+func decodeStats(body []byte) (Response, []string, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err != nil || object == nil {
+		return Response{}, nil, fmt.Errorf("%w: expected a json object", errUnexpectedStatsShape)
+	}
+
+	var response Response
+	missing := make([]string, 0)
+	for _, field := range statsFields {
+		raw, present := object[field.name]
+		if !present || string(raw) == "null" {
+			missing = append(missing, field.name)
+			continue
+		}
+
+		if err := json.Unmarshal(raw, field.value(&response)); err != nil {
+			return Response{}, nil, fmt.Errorf("%w: field %s is not a number", errUnexpectedStatsShape, field.name)
+		}
+	}
+
+	if len(missing) == len(statsFields) {
+		return Response{}, nil, fmt.Errorf("%w: none of the expected fields found", errUnexpectedStatsShape)
+	}
+
+	sort.Strings(missing)
+
+	return response, missing, nil
+}
@@ -0,0 +1,74 @@
+package trino
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// TLSSettings customize the tls connections to a cluster: ca_file replaces the system roots, cert_file and
+// key_file enable mutual tls, server_name overrides the name used for sni and verification
+type TLSSettings struct {
+	CAFile             string `yaml:"ca_file,omitempty"`
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	ServerName         string `yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+}
+
+func (s TLSSettings) isDefault() bool {
+	return s == TLSSettings{}
+}
+
+// modTimes returns the modification times of the ca, cert and key files, zero for the files not set or missing
+func (s TLSSettings) modTimes() []time.Time {
+	modTimes := make([]time.Time, 0, 3)
+	for _, file := range []string{s.CAFile, s.CertFile, s.KeyFile} {
+		var modTime time.Time
+		if file != "" {
+			if stat, err := os.Stat(file); err == nil {
+				modTime = stat.ModTime()
+			}
+		}
+		modTimes = append(modTimes, modTime)
+	}
+	return modTimes
+}
+
+func (s TLSSettings) config() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         s.ServerName,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+	}
+
+	if s.CAFile != "" {
+		data, err := ioutil.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in %s", s.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (s.CertFile == "") != (s.KeyFile == "") {
+		return nil, errors.New("tls cert_file and key_file must be set together")
+	}
+
+	if s.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,123 @@
+package trino
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClientTLSSettings(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"nodeVersion": {"version": "360"}}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	certificate := server.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600))
+
+	client := NewClient()
+	var info serverInfo
+
+	err = client.getJSON(context.Background(), ClusterInfo{Host: server.URL}, "/v1/info", &info)
+	require.Error(t, err)
+	require.Equal(t, reasonTls, errorReason(err))
+
+	err = client.getJSON(context.Background(), ClusterInfo{Host: server.URL, TLS: TLSSettings{CAFile: certFile}}, "/v1/info", &info)
+	require.True(t, isStatusCode(err, http.StatusUnauthorized))
+
+	mutual := TLSSettings{CAFile: certFile, CertFile: certFile, KeyFile: keyFile, ServerName: "example.com"}
+	require.NoError(t, client.getJSON(context.Background(), ClusterInfo{Host: server.URL, TLS: mutual}, "/v1/info", &info))
+
+	err = client.getJSON(context.Background(), ClusterInfo{Host: server.URL, TLS: TLSSettings{InsecureSkipVerify: true}}, "/v1/info", &info)
+	require.True(t, isStatusCode(err, http.StatusUnauthorized))
+}
+
+func TestClientReloadsRotatedTLSFiles(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"nodeVersion": {"version": "360"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, selfSignedCertificate(t), 0600))
+
+	client := NewClient()
+	cluster := ClusterInfo{Host: server.URL, TLS: TLSSettings{CAFile: caFile}}
+	var info serverInfo
+
+	err := client.getJSON(context.Background(), cluster, "/v1/info", &info)
+	require.Equal(t, reasonTls, errorReason(err))
+
+	rotated := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]})
+	require.NoError(t, ioutil.WriteFile(caFile, rotated, 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(caFile, later, later))
+
+	require.NoError(t, client.getJSON(context.Background(), cluster, "/v1/info", &info))
+
+	require.NoError(t, ioutil.WriteFile(caFile, []byte("not a certificate"), 0600))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(caFile, later, later))
+
+	require.NoError(t, client.getJSON(context.Background(), cluster, "/v1/info", &info))
+}
+
+// selfSignedCertificate returns a pem certificate not trusting the test servers
+func selfSignedCertificate(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "other"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestTLSSettingsInvalid(t *testing.T) {
+	_, err := TLSSettings{CertFile: "/cert.pem"}.config()
+	require.Error(t, err)
+
+	_, err = TLSSettings{CAFile: "/missing.pem"}.config()
+	require.Error(t, err)
+}
+
+func isStatusCode(err error, statusCode int) bool {
+	statusErr, ok := err.(statusCodeError)
+	return ok && statusErr.statusCode == statusCode
+}
@@ -0,0 +1,152 @@
+package trino
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileTokenSource reads a static bearer token (eg: a jwt) from a file, the file is read again when its
+// modification time changes so that rotated tokens are picked up without restarts
+type fileTokenSource struct {
+	path string
+
+	mutex   sync.Mutex
+	modTime time.Time
+	token   *oauth2.Token
+}
+
+func newFileTokenSource(path string) *fileTokenSource {
+	return &fileTokenSource{path: path}
+}
+
+func (s *fileTokenSource) Token() (*oauth2.Token, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.token != nil && info.ModTime().Equal(s.modTime) {
+		return s.token, nil
+	}
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return nil, fmt.Errorf("empty token file %s", s.path)
+	}
+
+	s.token = &oauth2.Token{AccessToken: value, TokenType: "Bearer"}
+	s.modTime = info.ModTime()
+
+	return s.token, nil
+}
+
+// OAuth2Settings configure the oauth2 client credentials flow, the client secret is read from
+// client_secret_file or from the client_secret_env environment variable
+type OAuth2Settings struct {
+	TokenUrl         string   `yaml:"token_url"`
+	ClientId         string   `yaml:"client_id"`
+	ClientSecretFile string   `yaml:"client_secret_file,omitempty"`
+	ClientSecretEnv  string   `yaml:"client_secret_env,omitempty"`
+	Scopes           []string `yaml:"scopes,omitempty"`
+}
+
+func (s OAuth2Settings) validate() error {
+	if s.TokenUrl == "" || s.ClientId == "" {
+		return errors.New("oauth2 token_url and client_id are required")
+	}
+	if s.ClientSecretFile != "" && s.ClientSecretEnv != "" {
+		return errors.New("oauth2 client_secret_file and client_secret_env are mutually exclusive")
+	}
+	return nil
+}
+
+// contextTokenSource is a token source requesting the tokens with the context of the scrape and the http
+// client of the cluster (eg: its tls settings)
+type contextTokenSource interface {
+	tokenWithContext(ctx context.Context, client *http.Client) (*oauth2.Token, error)
+}
+
+// clientCredentialsTokenSource obtains the tokens with the oauth2 client credentials flow, tokens are reused
+// and requested again shortly before their expiry. The client secret is read on the first token request and
+// read again when the modification time of client_secret_file (or the value of client_secret_env) changes,
+// the rotated secret requests a new token. A missing secret fails only the clusters using it
+type clientCredentialsTokenSource struct {
+	settings OAuth2Settings
+
+	mutex   sync.Mutex
+	config  *clientcredentials.Config
+	token   *oauth2.Token
+	modTime time.Time
+}
+
+func newClientCredentialsTokenSource(settings OAuth2Settings) *clientCredentialsTokenSource {
+	return &clientCredentialsTokenSource{settings: settings}
+}
+
+func (s *clientCredentialsTokenSource) Token() (*oauth2.Token, error) {
+	return s.tokenWithContext(context.Background(), &http.Client{Timeout: 10 * time.Second})
+}
+
+// tokenWithContext returns the current token, a new one is requested with ctx and client when the current one
+// is missing, expiring or obtained with a rotated secret
+func (s *clientCredentialsTokenSource) tokenWithContext(ctx context.Context, client *http.Client) (*oauth2.Token, error) {
+	var modTime time.Time
+	if s.settings.ClientSecretFile != "" {
+		info, err := os.Stat(s.settings.ClientSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		modTime = info.ModTime()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.config == nil || s.settings.ClientSecretFile == "" || !modTime.Equal(s.modTime) {
+		secret, err := readSecret(s.settings.ClientSecretFile, s.settings.ClientSecretEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		s.modTime = modTime
+
+		if s.config == nil || secret != s.config.ClientSecret {
+			s.config = &clientcredentials.Config{
+				ClientID:     s.settings.ClientId,
+				ClientSecret: secret,
+				TokenURL:     s.settings.TokenUrl,
+				Scopes:       s.settings.Scopes,
+			}
+			s.token = nil
+		}
+	}
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	token, err := s.config.Token(context.WithValue(ctx, oauth2.HTTPClient, client))
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+
+	return token, nil
+}
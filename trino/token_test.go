@@ -0,0 +1,174 @@
+package trino
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileTokenSourceReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(path, []byte("first\n"), 0600))
+
+	source := newFileTokenSource(path)
+
+	token, err := source.Token()
+	require.NoError(t, err)
+	require.Equal(t, "first", token.AccessToken)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("second"), 0600))
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	token, err = source.Token()
+	require.NoError(t, err)
+	require.Equal(t, "second", token.AccessToken)
+}
+
+func TestClientCredentialsTokenSource(t *testing.T) {
+	var issued int32
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+
+		clientId, clientSecret, ok := r.BasicAuth()
+		if !ok || clientId != "exporter" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "oauth-token", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	t.Cleanup(idp.Close)
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("secret\n"), 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer oauth-token" || r.Header.Get("X-Trino-User") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(statsResponse))
+	}))
+	t.Cleanup(server.Close)
+
+	source := newClientCredentialsTokenSource(OAuth2Settings{TokenUrl: idp.URL, ClientId: "exporter", ClientSecretFile: secretFile})
+	cluster := ClusterInfo{Host: server.URL, TokenSource: source}
+	client := NewClient()
+
+	var response Response
+	require.NoError(t, client.getUIJSON(context.Background(), cluster, "/ui/api/stats", &response))
+	require.NoError(t, client.getUIJSON(context.Background(), cluster, "/ui/api/stats", &response))
+	require.Equal(t, int32(1), atomic.LoadInt32(&issued))
+}
+
+func TestClientCredentialsTokenSourceReloadsSecret(t *testing.T) {
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, clientSecret, _ := r.BasicAuth()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "token-` + clientSecret + `", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	t.Cleanup(idp.Close)
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("first\n"), 0600))
+
+	source := newClientCredentialsTokenSource(OAuth2Settings{TokenUrl: idp.URL, ClientId: "exporter", ClientSecretFile: secretFile})
+
+	token, err := source.Token()
+	require.NoError(t, err)
+	require.Equal(t, "token-first", token.AccessToken)
+
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("second\n"), 0600))
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(secretFile, modTime, modTime))
+
+	token, err = source.Token()
+	require.NoError(t, err)
+	require.Equal(t, "token-second", token.AccessToken)
+
+	require.NoError(t, os.Setenv("TEST_CLIENT_SECRET", "first"))
+	t.Cleanup(func() { _ = os.Unsetenv("TEST_CLIENT_SECRET") })
+
+	source = newClientCredentialsTokenSource(OAuth2Settings{TokenUrl: idp.URL, ClientId: "exporter", ClientSecretEnv: "TEST_CLIENT_SECRET"})
+
+	token, err = source.Token()
+	require.NoError(t, err)
+	require.Equal(t, "token-first", token.AccessToken)
+
+	require.NoError(t, os.Setenv("TEST_CLIENT_SECRET", "second"))
+
+	token, err = source.Token()
+	require.NoError(t, err)
+	require.Equal(t, "token-second", token.AccessToken)
+}
+
+func TestAuthSettingsExclusiveModes(t *testing.T) {
+	require.Error(t, AuthSettings{PasswordEnv: "PASSWORD", TokenFile: "/token"}.Validate())
+	require.Error(t, AuthSettings{TokenFile: "/token", OAuth2: &OAuth2Settings{TokenUrl: "http://idp", ClientId: "exporter"}}.Validate())
+	require.Error(t, AuthSettings{OAuth2: &OAuth2Settings{ClientId: "exporter"}}.Validate())
+	require.NoError(t, AuthSettings{Username: "monitoring", TokenFile: "/token"}.Validate())
+}
+
+func TestClientCredentialsTokenSourceUsesClusterClient(t *testing.T) {
+	var issued int32
+	idp := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if atomic.AddInt32(&issued, 1) > 1 {
+			// only the first token is issued, the following requests hang until the client gives up
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "oauth-token", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	t.Cleanup(idp.Close)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer oauth-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"nodeVersion": {"version": "360"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	// the test servers share the certificate, trusted only by the tls settings of the cluster
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: idp.Certificate().Raw}), 0600))
+
+	settings := OAuth2Settings{TokenUrl: idp.URL, ClientId: "exporter", ClientSecretEnv: "TEST_CLUSTER_CLIENT_SECRET"}
+	require.NoError(t, os.Setenv("TEST_CLUSTER_CLIENT_SECRET", "secret"))
+	t.Cleanup(func() { _ = os.Unsetenv("TEST_CLUSTER_CLIENT_SECRET") })
+
+	client := NewClient()
+	cluster := ClusterInfo{Host: server.URL, TLS: TLSSettings{CAFile: caFile}, TokenSource: newClientCredentialsTokenSource(settings)}
+
+	var info serverInfo
+	require.NoError(t, client.getJSON(context.Background(), cluster, "/v1/info", &info))
+
+	cluster.TokenSource = newClientCredentialsTokenSource(settings)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.getJSON(ctx, cluster, "/v1/info", &info)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
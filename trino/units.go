@@ -0,0 +1,96 @@
+package trino
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// duration is an airlift duration serialized as string (eg: "1.50s", "3.20m") decoded in seconds
+type duration float64
+
+// dataSize is an airlift data size serialized as string (eg: "512B", "1.25GB") decoded in bytes
+type dataSize float64
+
+var durationUnits = map[string]float64{
+	"ns": 1e-9,
+	"us": 1e-6,
+	"ms": 1e-3,
+	"s":  1,
+	"m":  60,
+	"h":  60 * 60,
+	"d":  24 * 60 * 60,
+}
+
+var dataSizeUnits = map[string]float64{
+	"B":  1,
+	"kB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+	"TB": 1 << 40,
+	"PB": 1 << 50,
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalWithUnit(data, durationUnits)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s: %w", data, err)
+	}
+
+	*d = duration(value)
+	return nil
+}
+
+func (s *dataSize) UnmarshalJSON(data []byte) error {
+	value, err := unmarshalWithUnit(data, dataSizeUnits)
+	if err != nil {
+		return fmt.Errorf("invalid data size %s: %w", data, err)
+	}
+
+	*s = dataSize(value)
+	return nil
+}
+
+// unmarshalWithUnit decodes a json number or a string composed by a number and one of the given units
+func unmarshalWithUnit(data []byte, units map[string]float64) (float64, error) {
+	if string(data) == "null" {
+		return 0, nil
+	}
+
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		return number, nil
+	}
+
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return 0, err
+	}
+
+	return parseWithUnit(raw, units)
+}
+
+func parseWithUnit(raw string, units map[string]float64) (float64, error) {
+	raw = strings.TrimSpace(raw)
+
+	unitStart := strings.IndexFunc(raw, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+' && r != 'e' && r != 'E'
+	})
+
+	if unitStart <= 0 {
+		return 0, fmt.Errorf("missing unit in %q", raw)
+	}
+
+	multiplier, present := units[strings.TrimSpace(raw[unitStart:])]
+	if !present {
+		return 0, fmt.Errorf("unknown unit in %q", raw)
+	}
+
+	value, err := strconv.ParseFloat(raw[:unitStart], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return value * multiplier, nil
+}
@@ -0,0 +1,244 @@
+package trino
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+var workerLabels = []string{"cluster_name", "node_id", "uri"}
+
+var (
+	workerUp = newClusterDesc(
+		"worker", "up",
+		"Whether the worker could be scraped by the exporter.",
+		[]string{"cluster_name", "uri"},
+	)
+	workerUptimeSeconds = newClusterDesc(
+		"worker", "uptime_seconds",
+		"Uptime of the worker.",
+		workerLabels,
+	)
+	workerProcessors = newClusterDesc(
+		"worker", "processors",
+		"Available processors of the worker.",
+		workerLabels,
+	)
+	workerProcessCpuLoad = newClusterDesc(
+		"worker", "process_cpu_load",
+		"Cpu load of the worker process.",
+		workerLabels,
+	)
+	workerSystemCpuLoad = newClusterDesc(
+		"worker", "system_cpu_load",
+		"Cpu load of the worker host.",
+		workerLabels,
+	)
+	workerHeapUsedBytes = newClusterDesc(
+		"worker", "heap_used_bytes",
+		"Used heap memory of the worker.",
+		workerLabels,
+	)
+	workerHeapAvailableBytes = newClusterDesc(
+		"worker", "heap_available_bytes",
+		"Available heap memory of the worker.",
+		workerLabels,
+	)
+	workerNonHeapUsedBytes = newClusterDesc(
+		"worker", "non_heap_used_bytes",
+		"Used non heap memory of the worker.",
+		workerLabels,
+	)
+	workerMemoryPoolMaxBytes = newClusterDesc(
+		"worker", "memory_pool_max_bytes",
+		"Max bytes of the worker memory pool.",
+		append(workerLabels, "pool"),
+	)
+	workerMemoryPoolReservedBytes = newClusterDesc(
+		"worker", "memory_pool_reserved_bytes",
+		"Reserved bytes of the worker memory pool.",
+		append(workerLabels, "pool"),
+	)
+	workerMemoryPoolReservedRevocableBytes = newClusterDesc(
+		"worker", "memory_pool_reserved_revocable_bytes",
+		"Reserved revocable bytes of the worker memory pool.",
+		append(workerLabels, "pool"),
+	)
+)
+
+var DefaultWorkerJmxMetrics = []JmxMetric{
+	{MBean: "trino.execution.executor:name=TaskExecutor", Attribute: "Tasks", Name: "tasks", Help: "Tasks running on the worker."},
+	{MBean: "trino.execution.executor:name=TaskExecutor", Attribute: "RunningSplits", Name: "running_splits", Help: "Splits running on the worker."},
+	{MBean: "trino.execution.executor:name=TaskExecutor", Attribute: "WaitingSplits", Name: "waiting_splits", Help: "Splits waiting on the worker."},
+	{MBean: "java.lang:type=GarbageCollector,name=G1 Young Generation", Attribute: "CollectionCount", Name: "gc_young_collections_total", Type: JmxCounter, Help: "Young generation collections of the worker jvm."},
+	{MBean: "java.lang:type=GarbageCollector,name=G1 Young Generation", Attribute: "CollectionTime", Name: "gc_young_collection_time_milliseconds_total", Type: JmxCounter, Help: "Young generation collection time of the worker jvm."},
+	{MBean: "java.lang:type=GarbageCollector,name=G1 Old Generation", Attribute: "CollectionCount", Name: "gc_old_collections_total", Type: JmxCounter, Help: "Old generation collections of the worker jvm."},
+	{MBean: "java.lang:type=GarbageCollector,name=G1 Old Generation", Attribute: "CollectionTime", Name: "gc_old_collection_time_milliseconds_total", Type: JmxCounter, Help: "Old generation collection time of the worker jvm."},
+}
+
+// WorkerCollector scrapes directly the workers reported by the coordinator (/v1/node) reading their
+// status (/v1/status) and the configured MBean attributes (/v1/jmx/mbean), every worker failure is
+// reported by trino_cluster_worker_up. Up to parallelism workers of a cluster are scraped concurrently,
+// the credentials of the coordinator are sent to the workers only with sendCredentials
+type WorkerCollector struct {
+	client          *Client
+	jmxMappings     jmxMappings
+	parallelism     int
+	sendCredentials bool
+}
+
+func NewWorkerCollector(client *Client, jmxMetrics []JmxMetric, parallelism int, sendCredentials bool) (WorkerCollector, error) {
+	mappings, err := newJmxMappings("worker_jmx", jmxMetrics, workerLabels)
+	if err != nil {
+		return WorkerCollector{}, err
+	}
+
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	return WorkerCollector{
+		client:          client,
+		jmxMappings:     mappings,
+		parallelism:     parallelism,
+		sendCredentials: sendCredentials,
+	}, nil
+}
+
+func (c WorkerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workerUp
+	ch <- workerUptimeSeconds
+	ch <- workerProcessors
+	ch <- workerProcessCpuLoad
+	ch <- workerSystemCpuLoad
+	ch <- workerHeapUsedBytes
+	ch <- workerHeapAvailableBytes
+	ch <- workerNonHeapUsedBytes
+	ch <- workerMemoryPoolMaxBytes
+	ch <- workerMemoryPoolReservedBytes
+	ch <- workerMemoryPoolReservedRevocableBytes
+	c.jmxMappings.describe(ch)
+}
+
+func (c WorkerCollector) CollectCluster(ctx context.Context, name string, cluster ClusterInfo, out chan<- prometheus.Metric) error {
+	nodes, err := c.client.nodes(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	semaphore := make(chan struct{}, c.parallelism)
+
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(uri string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			c.scrapeWorker(ctx, name, c.worker(cluster, uri), out)
+		}(node.Uri)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// worker returns the settings to reach a worker, the same of the coordinator except the server name override
+// identifying the coordinator only and, unless sent on purpose, the credentials
+func (c WorkerCollector) worker(cluster ClusterInfo, uri string) ClusterInfo {
+	worker := cluster
+	worker.Host = uri
+	worker.TLS.ServerName = ""
+
+	if !c.sendCredentials {
+		worker.Credentials = Credentials{Username: cluster.Credentials.Username}
+		worker.TokenSource = nil
+	}
+
+	return worker
+}
+
+func (c WorkerCollector) scrapeWorker(ctx context.Context, name string, worker ClusterInfo, out chan<- prometheus.Metric) {
+	if err := c.collectWorker(ctx, name, worker, out); err != nil {
+		logrus.Warnf("unable to scrape worker %s of cluster %s: %s", worker.Host, name, err)
+		out <- prometheus.MustNewConstMetric(workerUp, prometheus.GaugeValue, 0, name, worker.Host)
+		return
+	}
+
+	out <- prometheus.MustNewConstMetric(workerUp, prometheus.GaugeValue, 1, name, worker.Host)
+}
+
+func (c WorkerCollector) collectWorker(ctx context.Context, name string, worker ClusterInfo, out chan<- prometheus.Metric) error {
+	status, err := c.client.status(ctx, worker)
+	if err != nil {
+		return err
+	}
+
+	labelValues := []string{name, status.NodeId, worker.Host}
+
+	out <- prometheus.MustNewConstMetric(workerUptimeSeconds, prometheus.GaugeValue, float64(status.Uptime), labelValues...)
+	out <- prometheus.MustNewConstMetric(workerProcessors, prometheus.GaugeValue, status.MemoryInfo.AvailableProcessors, labelValues...)
+	out <- prometheus.MustNewConstMetric(workerProcessCpuLoad, prometheus.GaugeValue, status.ProcessCpuLoad, labelValues...)
+	out <- prometheus.MustNewConstMetric(workerSystemCpuLoad, prometheus.GaugeValue, status.SystemCpuLoad, labelValues...)
+	out <- prometheus.MustNewConstMetric(workerHeapUsedBytes, prometheus.GaugeValue, status.HeapUsed, labelValues...)
+	out <- prometheus.MustNewConstMetric(workerHeapAvailableBytes, prometheus.GaugeValue, status.HeapAvailable, labelValues...)
+	out <- prometheus.MustNewConstMetric(workerNonHeapUsedBytes, prometheus.GaugeValue, status.NonHeapUsed, labelValues...)
+
+	for pool, info := range status.MemoryInfo.pools() {
+		poolLabelValues := append(labelValues, pool)
+		out <- prometheus.MustNewConstMetric(workerMemoryPoolMaxBytes, prometheus.GaugeValue, info.MaxBytes, poolLabelValues...)
+		out <- prometheus.MustNewConstMetric(workerMemoryPoolReservedBytes, prometheus.GaugeValue, info.ReservedBytes, poolLabelValues...)
+		out <- prometheus.MustNewConstMetric(workerMemoryPoolReservedRevocableBytes, prometheus.GaugeValue, info.ReservedRevocableBytes, poolLabelValues...)
+	}
+
+	return c.jmxMappings.collect(ctx, c.client, worker, out, labelValues...)
+}
+
+func (c *Client) status(ctx context.Context, node ClusterInfo) (nodeStatus, error) {
+	var status nodeStatus
+	if err := c.getJSON(ctx, node, "/v1/status", &status); err != nil {
+		return nodeStatus{}, err
+	}
+
+	return status, nil
+}
+
+type nodeStatus struct {
+	NodeId         string         `json:"nodeId"`
+	Uptime         duration       `json:"uptime"`
+	MemoryInfo     nodeMemoryInfo `json:"memoryInfo"`
+	ProcessCpuLoad float64        `json:"processCpuLoad"`
+	SystemCpuLoad  float64        `json:"systemCpuLoad"`
+	HeapUsed       float64        `json:"heapUsed"`
+	HeapAvailable  float64        `json:"heapAvailable"`
+	NonHeapUsed    float64        `json:"nonHeapUsed"`
+}
+
+// nodeMemoryInfo contains a map of pools in older versions and only the general pool in newer ones
+type nodeMemoryInfo struct {
+	AvailableProcessors float64                   `json:"availableProcessors"`
+	Pools               map[string]memoryPoolInfo `json:"pools"`
+	Pool                *memoryPoolInfo           `json:"pool"`
+}
+
+func (m nodeMemoryInfo) pools() map[string]memoryPoolInfo {
+	if m.Pool != nil {
+		return map[string]memoryPoolInfo{defaultMemoryPool: *m.Pool}
+	}
+	return m.Pools
+}
+
+type memoryPoolInfo struct {
+	MaxBytes               float64 `json:"maxBytes"`
+	ReservedBytes          float64 `json:"reservedBytes"`
+	ReservedRevocableBytes float64 `json:"reservedRevocableBytes"`
+}
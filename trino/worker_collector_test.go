@@ -0,0 +1,94 @@
+package trino
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const workerStatusResponse = `{"nodeId": "worker-1", "uptime": "2.00h", "processCpuLoad": 0.5, "systemCpuLoad": 0.75,
+	"heapUsed": 1024, "heapAvailable": 4096, "nonHeapUsed": 256,
+	"memoryInfo": {"availableProcessors": 8, "pool": {"maxBytes": 2048, "reservedBytes": 512, "reservedRevocableBytes": 0}}}`
+
+func newWorkerServer(t *testing.T, authorized *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			atomic.AddInt32(authorized, 1)
+		}
+
+		switch r.URL.Path {
+		case "/v1/status":
+			_, _ = w.Write([]byte(workerStatusResponse))
+		case "/v1/jmx/mbean/trino.execution.executor:name=TaskExecutor":
+			_, _ = w.Write([]byte(`{"objectName": "trino.execution.executor:name=TaskExecutor", "attributes": [{"name": "Tasks", "value": 3}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWorkerCollector(t *testing.T) {
+	var authorized int32
+	worker := newWorkerServer(t, &authorized)
+
+	failing := httptest.NewServer(nil)
+	failing.Close()
+
+	coordinator := newTrinoServer(t, map[string]string{
+		"/v1/node": fmt.Sprintf(`[{"uri": "%s"}, {"uri": "%s"}]`, worker.URL, failing.URL),
+	})
+
+	collector, err := NewWorkerCollector(NewClient(), []JmxMetric{
+		{MBean: "trino.execution.executor:name=TaskExecutor", Attribute: "Tasks", Name: "tasks", Help: "Tasks running on the worker."},
+	}, 2, false)
+	require.NoError(t, err)
+
+	cluster := ClusterInfo{Host: coordinator.URL, Credentials: Credentials{Username: "monitoring", Password: "secret"}}
+	exporter := NewExporter(staticClusterProvider{"test": cluster}, 1, collector)
+
+	expected := fmt.Sprintf(`
+# HELP trino_cluster_worker_heap_used_bytes Used heap memory of the worker.
+# TYPE trino_cluster_worker_heap_used_bytes gauge
+trino_cluster_worker_heap_used_bytes{cluster_name="test",node_id="worker-1",uri="%[1]s"} 1024
+# HELP trino_cluster_worker_jmx_tasks Tasks running on the worker.
+# TYPE trino_cluster_worker_jmx_tasks gauge
+trino_cluster_worker_jmx_tasks{cluster_name="test",node_id="worker-1",uri="%[1]s"} 3
+# HELP trino_cluster_worker_memory_pool_max_bytes Max bytes of the worker memory pool.
+# TYPE trino_cluster_worker_memory_pool_max_bytes gauge
+trino_cluster_worker_memory_pool_max_bytes{cluster_name="test",node_id="worker-1",pool="general",uri="%[1]s"} 2048
+# HELP trino_cluster_worker_up Whether the worker could be scraped by the exporter.
+# TYPE trino_cluster_worker_up gauge
+trino_cluster_worker_up{cluster_name="test",uri="%[1]s"} 1
+trino_cluster_worker_up{cluster_name="test",uri="%[2]s"} 0
+`, worker.URL, failing.URL)
+
+	require.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected),
+		"trino_cluster_worker_heap_used_bytes", "trino_cluster_worker_jmx_tasks", "trino_cluster_worker_memory_pool_max_bytes", "trino_cluster_worker_up"))
+	require.Equal(t, int32(0), atomic.LoadInt32(&authorized))
+}
+
+func TestWorkerCollectorSendsCredentials(t *testing.T) {
+	var authorized int32
+	worker := newWorkerServer(t, &authorized)
+
+	coordinator := newTrinoServer(t, map[string]string{
+		"/v1/node": fmt.Sprintf(`[{"uri": "%s"}]`, worker.URL),
+	})
+
+	collector, err := NewWorkerCollector(NewClient(), nil, 1, true)
+	require.NoError(t, err)
+
+	cluster := ClusterInfo{Host: coordinator.URL, Credentials: Credentials{Username: "monitoring", Password: "secret"}}
+	exporter := NewExporter(staticClusterProvider{"test": cluster}, 1, collector)
+
+	require.Equal(t, 1, testutil.CollectAndCount(exporter, "trino_cluster_worker_up"))
+	require.Equal(t, int32(1), atomic.LoadInt32(&authorized))
+}